@@ -0,0 +1,63 @@
+package gee
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AuthUserKey 认证通过的用户名在 Context 中的 key
+const AuthUserKey = "gee.user"
+
+// Accounts 用户名 -> 密码
+type Accounts map[string]string
+
+type basicCredential struct {
+	user   string
+	digest [sha256.Size]byte
+}
+
+// BasicAuth HTTP Basic 认证, 认证失败返回 401 并要求浏览器弹出登录框
+func BasicAuth(accounts Accounts) HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+// BasicAuthForRealm 同 BasicAuth, realm 为空时使用 "Authorization Required"
+func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunc {
+	if len(accounts) == 0 {
+		panic("gee: BasicAuth accounts must not be empty")
+	}
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	realm = "Basic realm=" + strconv.Quote(realm)
+	// 比较完整 Authorization 头的摘要, 耗时与用户名是否存在无关
+	credentials := make([]basicCredential, 0, len(accounts))
+	for user, password := range accounts {
+		if user == "" || strings.Contains(user, ":") {
+			panic("gee: invalid BasicAuth user " + strconv.Quote(user))
+		}
+		header := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+		credentials = append(credentials, basicCredential{user, sha256.Sum256([]byte(header))})
+	}
+
+	return func(c *Context) {
+		digest := sha256.Sum256([]byte(c.Req.Header.Get("Authorization")))
+		user := ""
+		for _, cred := range credentials {
+			if subtle.ConstantTimeCompare(digest[:], cred.digest[:]) == 1 {
+				user = cred.user
+			}
+		}
+		if user == "" {
+			c.SetHeader("WWW-Authenticate", realm)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set(AuthUserKey, user)
+		c.Next()
+	}
+}
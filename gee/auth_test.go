@@ -0,0 +1,125 @@
+package gee
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBasicAuth(t *testing.T) {
+	r := New()
+	r.GET("/public", func(c *Context) { c.String(http.StatusOK, "public") })
+	admin := r.Group("/admin")
+	admin.Use(BasicAuth(Accounts{"tiam": "secret"}))
+	admin.GET("/", func(c *Context) { c.String(http.StatusOK, c.GetString(AuthUserKey)) })
+
+	do := func(path, user, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := do("/public", "", ""); w.Code != http.StatusOK {
+		t.Fatalf("public route status = %d", w.Code)
+	}
+	if w := do("/admin/", "tiam", "wrong"); w.Code != http.StatusUnauthorized ||
+		w.Header().Get("WWW-Authenticate") != `Basic realm="Authorization Required"` {
+		t.Fatalf("wrong password: %d %v", w.Code, w.Header())
+	}
+	if w := do("/admin/", "tiam", "secret"); w.Code != http.StatusOK || w.Body.String() != "tiam" {
+		t.Fatalf("valid credentials: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestJWTParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	conf := &JWTConfig{
+		Keys: map[string]interface{}{
+			"old": []byte("old-secret"),
+			"new": []byte("new-secret"),
+			"rsa": &rsaKey.PublicKey,
+			"ed":  edPub,
+		},
+		Issuer:   "gee",
+		Audience: "api",
+	}
+	now := time.Now().Unix()
+	valid := JWTClaims{"sub": "tiam", "iss": "gee", "aud": []string{"web", "api"}, "exp": now + 60}
+
+	sign := func(claims JWTClaims, alg string, key interface{}, kid string) string {
+		token, err := SignJWT(claims, alg, key, kid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	cases := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"hs256 old key", sign(valid, HS256, []byte("old-secret"), "old"), nil},
+		{"hs256 new key", sign(valid, HS256, []byte("new-secret"), "new"), nil},
+		{"rs256", sign(valid, RS256, rsaKey, "rsa"), nil},
+		{"eddsa", sign(valid, EdDSA, edPriv, "ed"), nil},
+		{"wrong secret", sign(valid, HS256, []byte("other"), "new"), ErrTokenSignature},
+		{"unknown kid", sign(valid, HS256, []byte("new-secret"), "missing"), ErrTokenUnknownKey},
+		// 用 RSA 公钥对应的 kid 声明 HS256, 密钥类型不匹配
+		{"algorithm confusion", sign(valid, HS256, []byte("x"), "rsa"), ErrTokenAlgorithm},
+		{"expired", sign(JWTClaims{"iss": "gee", "aud": "api", "exp": now - 1}, HS256, []byte("new-secret"), "new"), ErrTokenExpired},
+		{"not valid yet", sign(JWTClaims{"iss": "gee", "aud": "api", "nbf": now + 60}, HS256, []byte("new-secret"), "new"), ErrTokenNotValidYet},
+		{"issuer", sign(JWTClaims{"iss": "other", "aud": "api"}, HS256, []byte("new-secret"), "new"), ErrTokenIssuer},
+		{"audience", sign(JWTClaims{"iss": "gee", "aud": "web"}, HS256, []byte("new-secret"), "new"), ErrTokenAudience},
+		{"malformed", "a.b", ErrTokenMalformed},
+		{"alg none", "eyJhbGciOiJub25lIn0.eyJzdWIiOiJ0aWFtIn0.", ErrTokenAlgorithm},
+	}
+	for _, tc := range cases {
+		claims, err := conf.Parse(tc.token)
+		if err != tc.err {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.err)
+		}
+		if err == nil && claims.Subject() != "tiam" {
+			t.Errorf("%s: subject = %q", tc.name, claims.Subject())
+		}
+	}
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	r := New()
+	r.GET("/public", func(c *Context) { c.String(http.StatusOK, "public") })
+	private := r.Group("/api")
+	private.Use(JWT(JWTConfig{Key: secret}))
+	private.GET("/me", func(c *Context) { c.String(http.StatusOK, c.JWTClaims().Subject()) })
+
+	token, _ := SignJWT(JWTClaims{"sub": "tiam"}, HS256, secret, "")
+	do := func(path, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := do("/public", ""); w.Code != http.StatusOK {
+		t.Fatalf("public route status = %d", w.Code)
+	}
+	if w := do("/api/me", ""); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("missing token: %d", w.Code)
+	}
+	if w := do("/api/me", "Bearer "+token); w.Code != http.StatusOK || w.Body.String() != "tiam" {
+		t.Fatalf("valid token: %d %q", w.Code, w.Body.String())
+	}
+}
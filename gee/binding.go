@@ -0,0 +1,298 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MIMEJSON              = "application/json"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+)
+
+// Bind 根据请求方法和 Content-Type 选择解析方式, 解析后进行校验
+// GET/DELETE 等无请求体的方法解析 Query, 其余按 Content-Type 解析 JSON 或 Form
+func (c *Context) Bind(obj interface{}) error {
+	if c.Method == http.MethodGet || c.Method == http.MethodHead || c.Method == http.MethodDelete {
+		return c.BindQuery(obj)
+	}
+	switch contentType(c.Req) {
+	case MIMEJSON:
+		return c.BindJSON(obj)
+	case MIMEPOSTForm, MIMEMultipartPOSTForm:
+		return c.BindForm(obj)
+	default:
+		return c.BindQuery(obj)
+	}
+}
+
+// BindJSON 将请求体按 json tag 解析到 obj
+func (c *Context) BindJSON(obj interface{}) error {
+	if c.Req.Body == nil {
+		return errors.New("gee: invalid request body")
+	}
+	if err := json.NewDecoder(c.Req.Body).Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// BindQuery 将 URL 中的查询参数按 form tag 解析到 obj
+func (c *Context) BindQuery(obj interface{}) error {
+	if err := mapValues(obj, formValues(c.Req.URL.Query()), "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// BindForm 将表单(包括 Query 和 multipart)按 form tag 解析到 obj
+func (c *Context) BindForm(obj interface{}) error {
+	if err := c.Req.ParseMultipartForm(c.maxMultipartMemory()); err != nil && err != http.ErrNotMultipart {
+		return err
+	}
+	if err := mapValues(obj, formValues(c.Req.Form), "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// BindURI 将路由参数按 uri tag 解析到 obj
+func (c *Context) BindURI(obj interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		values[p.Key] = []string{p.Value}
+	}
+	if err := mapValues(obj, formValues(values), "uri"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// BindHeader 将请求头按 header tag 解析到 obj
+func (c *Context) BindHeader(obj interface{}) error {
+	if err := mapValues(obj, headerValues(c.Req.Header), "header"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// 去掉 Content-Type 中的参数部分, 例如 application/json; charset=utf-8
+func contentType(req *http.Request) string {
+	ct := req.Header.Get("Content-Type")
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	return strings.TrimSpace(ct)
+}
+
+// http.Header 的 key 是规范化的, 查找时同样需要规范化
+type headerValues http.Header
+
+func (h headerValues) lookup(key string) ([]string, bool) {
+	vs, ok := h[textproto.CanonicalMIMEHeaderKey(key)]
+	return vs, ok
+}
+
+type formValues map[string][]string
+
+func (f formValues) lookup(key string) ([]string, bool) {
+	vs, ok := f[key]
+	return vs, ok
+}
+
+type valueSource interface {
+	lookup(key string) ([]string, bool)
+}
+
+// 按 tag 将 values 映射到 obj 的字段, obj 必须是结构体指针
+// tag 格式: `form:"name,default=value"`, 为 "-" 时跳过该字段, 缺省时使用字段名
+func mapValues(obj interface{}, src valueSource, tag string) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("gee: binding requires a non-nil pointer to struct")
+	}
+	return mapStruct(v.Elem(), src, tag)
+}
+
+func mapStruct(v reflect.Value, src valueSource, tag string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		// 未导出字段跳过, 但仍解析内嵌结构体中导出的字段
+		if sf.PkgPath != "" && !(sf.Anonymous && sf.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		field := v.Field(i)
+		name, opts := sf.Tag.Get(tag), ""
+		if name == "-" {
+			continue
+		}
+		if j := strings.IndexByte(name, ','); j >= 0 {
+			name, opts = name[:j], name[j+1:]
+		}
+		// 没有 tag 的嵌套结构体递归解析, time.Time 作为普通值处理
+		if name == "" && isStruct(sf.Type) {
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					field.Set(reflect.New(sf.Type.Elem()))
+				}
+				field = field.Elem()
+			}
+			if err := mapStruct(field, src, tag); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		vs, ok := src.lookup(name)
+		if !ok || len(vs) == 0 {
+			def, has := tagOption(opts, "default")
+			if !has {
+				continue
+			}
+			vs = []string{def}
+		}
+		if err := setField(field, sf, vs); err != nil {
+			return fmt.Errorf("gee: binding field %s: %w", sf.Name, err)
+		}
+	}
+	return nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// 查找 tag 中的 key=value 选项
+func tagOption(opts string, key string) (string, bool) {
+	for _, opt := range strings.Split(opts, ",") {
+		if strings.HasPrefix(opt, key+"=") {
+			return opt[len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+func setField(field reflect.Value, sf reflect.StructField, vs []string) error {
+	switch field.Kind() {
+	case reflect.Ptr:
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setField(field.Elem(), sf, vs)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(field.Type(), len(vs), len(vs))
+		for i, s := range vs {
+			if err := setValue(slice.Index(i), sf, s); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(vs) != field.Len() {
+			return fmt.Errorf("%q is not valid value for %s", vs, field.Type())
+		}
+		for i, s := range vs {
+			if err := setValue(field.Index(i), sf, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return setValue(field, sf, vs[0])
+	}
+}
+
+// 将字符串转换为 field 的类型并赋值
+func setValue(field reflect.Value, sf reflect.StructField, s string) error {
+	if field.Type() == timeType {
+		layout := sf.Tag.Get("time_format")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		if s == "" {
+			return nil
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			s = "false"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			s = "0"
+		}
+		i, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			s = "0"
+		}
+		u, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			s = "0"
+		}
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Ptr:
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setValue(field.Elem(), sf, s)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
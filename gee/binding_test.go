@@ -0,0 +1,99 @@
+package gee
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type signupForm struct {
+	Name   string   `form:"name" json:"name" binding:"required,min=3,max=8"`
+	Email  string   `form:"email" json:"email" binding:"required,email"`
+	Age    int      `form:"age" json:"age" binding:"omitempty,min=18"`
+	Role   string   `form:"role,default=user" json:"role" binding:"oneof=user admin"`
+	Tags   []string `form:"tag" json:"tags" binding:"max=2"`
+	Ignore string   `form:"-" json:"-"`
+}
+
+func TestBindQueryAndForm(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?name=tiam&email=tiam@example.com&age=20&tag=a&tag=b", nil)
+	c := NewContext(httptest.NewRecorder(), req)
+	var form signupForm
+	if err := c.Bind(&form); err != nil {
+		t.Fatal(err)
+	}
+	if form.Name != "tiam" || form.Age != 20 || form.Role != "user" || len(form.Tags) != 2 {
+		t.Fatalf("unexpected binding result %+v", form)
+	}
+
+	body := url.Values{"name": {"chuyu"}, "email": {"chuyu@example.com"}, "role": {"admin"}}
+	req = httptest.NewRequest("POST", "/", strings.NewReader(body.Encode()))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	c = NewContext(httptest.NewRecorder(), req)
+	form = signupForm{}
+	if err := c.Bind(&form); err != nil {
+		t.Fatal(err)
+	}
+	if form.Name != "chuyu" || form.Role != "admin" {
+		t.Fatalf("unexpected binding result %+v", form)
+	}
+}
+
+func TestBindJSONValidation(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"ab","email":"bad","age":10,"role":"root"}`))
+	req.Header.Set("Content-Type", MIMEJSON+"; charset=utf-8")
+	c := NewContext(httptest.NewRecorder(), req)
+	var form signupForm
+	err := c.Bind(&form)
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expect ValidationErrors, got %v", err)
+	}
+	tags := map[string]string{}
+	for _, e := range verrs {
+		tags[e.Field] = e.Tag
+	}
+	expect := map[string]string{"Name": "min", "Email": "email", "Age": "min", "Role": "oneof"}
+	for field, tag := range expect {
+		if tags[field] != tag {
+			t.Fatalf("field %s: expect rule %s, got %q", field, tag, tags[field])
+		}
+	}
+}
+
+func TestBindURIAndHeader(t *testing.T) {
+	var got struct {
+		ID    int    `uri:"id" binding:"required"`
+		Token string `header:"x-token" binding:"omitempty,len=4"`
+	}
+	r := New()
+	r.GET("/user/:id", func(c *Context) {
+		if err := c.BindURI(&got); err != nil {
+			c.JSON(http.StatusBadRequest, H{"errors": err})
+			return
+		}
+		if err := c.BindHeader(&got); err != nil {
+			c.JSON(http.StatusBadRequest, H{"errors": err})
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/user/42", nil)
+	req.Header.Set("X-Token", "abcd")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || got.ID != 42 || got.Token != "abcd" {
+		t.Fatalf("unexpected result %d %+v", w.Code, got)
+	}
+
+	req.Header.Set("X-Token", "abc")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"tag":"len"`) {
+		t.Fatalf("expect 400 with len error, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"html/template"
+	"log"
 	"net/http"
 	"qitian/gee"
 	"time"
@@ -21,10 +22,12 @@ func FormatAsDate(t time.Time) string {
 func main() {
 	r := gee.New()
 	r.Use(gee.Logger(), gee.Recovery())
-	r.SetFuncMap(template.FuncMap{
+	_ = r.SetFuncMap(template.FuncMap{
 		"FormatAsDate": FormatAsDate,
 	})
-	r.LoadHTMLGlob("./templates/*")
+	if err := r.LoadHTMLGlob("./templates/*"); err != nil {
+		log.Fatal(err)
+	}
 	r.Static("/assets", "./static")
 
 	stu1 := student{
@@ -59,5 +62,6 @@ func main() {
 		c.String(http.StatusOK, names[100])
 	})
 
+	r.ShutdownOnSignal(5 * time.Second)
 	_ = r.Run(":9999")
 }
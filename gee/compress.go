@@ -0,0 +1,277 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressConfig 响应压缩配置, 零值即为默认配置
+type CompressConfig struct {
+	Level                int      // 压缩级别, 默认 gzip.DefaultCompression
+	MinLength            int      // 响应体小于该长度时不压缩, 默认 1024, 流式响应(调用 Flush)不受限制
+	ExcludedContentTypes []string // 不压缩的 Content-Type 前缀, 默认为常见的已压缩格式
+	ExcludedPaths        []string // 不压缩的路径前缀
+}
+
+var defaultExcludedContentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/", "audio/", "font/woff", "application/pdf", "application/zip", "application/gzip",
+	"application/x-gzip", "application/x-7z-compressed", "application/x-rar-compressed", "application/wasm",
+}
+
+// 可以复用的压缩器, gzip.Writer 与 flate.Writer 都满足该接口
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compress 根据 Accept-Encoding 使用 gzip 或 deflate 压缩响应
+func Compress(conf CompressConfig) HandlerFunc {
+	if conf.Level == 0 {
+		conf.Level = gzip.DefaultCompression
+	}
+	if conf.Level < gzip.HuffmanOnly || conf.Level > gzip.BestCompression {
+		panic("gee: invalid compression level " + strconv.Itoa(conf.Level))
+	}
+	if conf.MinLength == 0 {
+		conf.MinLength = 1024
+	}
+	if conf.ExcludedContentTypes == nil {
+		conf.ExcludedContentTypes = defaultExcludedContentTypes
+	}
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(io.Discard, conf.Level)
+			return w
+		}},
+		"deflate": {New: func() interface{} {
+			w, _ := flate.NewWriter(io.Discard, conf.Level)
+			return w
+		}},
+	}
+
+	return func(c *Context) {
+		if c.Method == http.MethodHead || c.Req.Header.Get("Upgrade") != "" || conf.excludedPath(c.Path) {
+			c.Next()
+			return
+		}
+		c.W.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.Req.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: c.W, conf: &conf, encoding: encoding, pool: pools[encoding]}
+		c.W = cw
+		finished := false
+		defer func() {
+			if !finished {
+				// handler panic, 丢弃尚未发送的内容, 由外层的 Recovery 重新响应
+				cw.abort()
+			}
+			c.W = cw.ResponseWriter
+		}()
+		c.Next()
+		cw.close()
+		finished = true
+	}
+}
+
+func (conf *CompressConfig) excludedPath(path string) bool {
+	for _, prefix := range conf.ExcludedPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 返回 gzip、deflate 或空字符串, q 值相同时优先 gzip, 支持 * 与 q=0
+func negotiateEncoding(header string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					weight = v
+				}
+			}
+		}
+		q[name] = weight
+	}
+	best, bestQ := "", 0.0
+	for _, name := range []string{"gzip", "deflate"} {
+		weight, ok := q[name]
+		if !ok {
+			weight, ok = q["*"]
+		}
+		if ok && weight > bestQ {
+			best, bestQ = name, weight
+		}
+	}
+	return best
+}
+
+const (
+	compressUndecided = iota
+	compressOn
+	compressOff
+)
+
+// 先缓存响应体, 达到 MinLength、调用 Flush 或请求结束时再决定是否压缩,
+// 决定之前仍可以修改响应头
+type compressWriter struct {
+	ResponseWriter
+	conf     *CompressConfig
+	encoding string
+	pool     *sync.Pool
+	state    int
+	buf      bytes.Buffer
+	w        compressor
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.state == compressUndecided {
+		w.buf.Write(data)
+		if w.buf.Len() < w.conf.MinLength {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.state == compressOn {
+		return w.w.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if w.state == compressUndecided {
+		_ = w.decide(false)
+	}
+}
+
+func (w *compressWriter) Written() bool {
+	return w.state != compressUndecided && w.ResponseWriter.Written()
+}
+
+// 流式响应在第一次 Flush 时决定是否压缩, 之后每次 Flush 都会刷新压缩器
+func (w *compressWriter) Flush() {
+	if w.state == compressUndecided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.state == compressOn {
+		_ = w.w.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.state = compressOff
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// 根据响应头和已缓存的内容决定是否压缩, 并写出缓存;
+// force 表示缓存已达到 MinLength 或是流式响应, 不再检查长度
+func (w *compressWriter) decide(force bool) error {
+	h := w.Header()
+	if h.Get("Content-Type") == "" && w.buf.Len() > 0 {
+		// 压缩后 net/http 无法再根据内容推断类型
+		h.Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+	if w.shouldCompress(force) {
+		w.state = compressOn
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		w.w = w.pool.Get().(compressor)
+		w.w.Reset(w.ResponseWriter)
+	} else {
+		w.state = compressOff
+		w.ResponseWriter.WriteHeaderNow()
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.state == compressOn {
+		_, err = w.w.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressWriter) shouldCompress(force bool) bool {
+	status := w.Status()
+	h := w.Header()
+	if !bodyAllowedForStatus(status) || status == http.StatusPartialContent ||
+		h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if !force && w.buf.Len() < w.conf.MinLength {
+		return false
+	}
+	contentType := strings.ToLower(h.Get("Content-Type"))
+	for _, excluded := range w.conf.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// 请求正常结束, 写出剩余内容并归还压缩器
+func (w *compressWriter) close() {
+	if w.state == compressUndecided {
+		_ = w.decide(false)
+	}
+	if w.state == compressOn {
+		_ = w.w.Close()
+		w.release()
+	}
+}
+
+func (w *compressWriter) abort() {
+	w.buf.Reset()
+	if w.state == compressOn {
+		_ = w.w.Close()
+		w.release()
+	}
+	w.state = compressOff
+}
+
+func (w *compressWriter) release() {
+	w.w.Reset(io.Discard)
+	w.pool.Put(w.w)
+	w.w = nil
+}
@@ -0,0 +1,104 @@
+package gee
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                        "",
+		"gzip, deflate, br":       "gzip",
+		"deflate":                 "deflate",
+		"gzip;q=0.5, deflate":     "deflate",
+		"*":                       "gzip",
+		"gzip;q=0, *":             "deflate",
+		"identity":                "",
+		"GZIP;q=0.8, deflate;q=0": "gzip",
+	}
+	for header, want := range cases {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("gee ", 1024)
+	r := New()
+	r.Use(Compress(CompressConfig{}))
+	r.GET("/large", func(c *Context) { c.JSON(http.StatusOK, H{"data": large}) })
+	r.GET("/small", func(c *Context) { c.String(http.StatusOK, "small") })
+	r.GET("/png", func(c *Context) {
+		c.SetHeader("Content-Type", "image/png")
+		c.Data(http.StatusOK, []byte(large))
+	})
+	r.GET("/stream", func(c *Context) {
+		i := 0
+		c.Stream(func(w io.Writer) bool {
+			io.WriteString(w, "chunk\n")
+			i++
+			return i < 3
+		})
+	})
+
+	get := func(path, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/large", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" ||
+		w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(zr)
+	if !strings.Contains(string(body), large) {
+		t.Fatal("gzip body mismatch")
+	}
+
+	w = get("/large", "deflate")
+	body, _ = io.ReadAll(flate.NewReader(w.Body))
+	if w.Header().Get("Content-Encoding") != "deflate" || !strings.Contains(string(body), large) {
+		t.Fatalf("deflate response: %v", w.Header())
+	}
+
+	for _, path := range []string{"/small", "/png"} {
+		w = get(path, "gzip")
+		if w.Header().Get("Content-Encoding") != "" || w.Code != http.StatusOK {
+			t.Fatalf("%s shouldn't be compressed: %v", path, w.Header())
+		}
+	}
+	if w = get("/small", "gzip"); w.Body.String() != "small" {
+		t.Fatalf("small body = %q", w.Body.String())
+	}
+
+	w = get("/stream", "gzip")
+	if !w.Flushed || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("stream should be compressed and flushed: %v", w.Header())
+	}
+	zr, err = gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(zr)
+	if string(body) != "chunk\nchunk\nchunk\n" {
+		t.Fatalf("stream body = %q", body)
+	}
+
+	if w = get("/large", ""); w.Header().Get("Content-Encoding") != "" || !strings.Contains(w.Body.String(), large) {
+		t.Fatal("response shouldn't be compressed without Accept-Encoding")
+	}
+}
@@ -0,0 +1,213 @@
+package gee
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 路由参数约束, 在 trie 查找时检查, 不满足约束的请求继续尝试其他路由:
+//
+//	/users/:id<int>        类型约束, 支持 int、uint、uuid、alpha
+//	/users/{id:[0-9]+}     正则约束, 匹配整个路径段
+//	/users/{id}            等同于 /users/:id
+var paramTypes = map[string]func(string) bool{
+	"int": func(s string) bool {
+		_, err := strconv.ParseInt(s, 10, 0)
+		return err == nil
+	},
+	"uint": func(s string) bool {
+		_, err := strconv.ParseUint(s, 10, 0)
+		return err == nil
+	},
+	"uuid": func(s string) bool {
+		_, err := parseUUID(s)
+		return err == nil
+	},
+	"alpha": func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			if c := s[i] | 0x20; c < 'a' || c > 'z' {
+				return false
+			}
+		}
+		return s != ""
+	},
+}
+
+// paramSpec 路由中的一个参数段
+type paramSpec struct {
+	key   string
+	kind  nodeKind // param 或 catchAll
+	typ   string   // 类型约束, 例如 int
+	regex string   // 正则约束
+}
+
+// 约束的唯一标识, 同一位置约束相同的参数共用一个节点
+func (s paramSpec) constraint() string {
+	switch {
+	case s.typ != "":
+		return "<" + s.typ + ">"
+	case s.regex != "":
+		return "{" + s.regex + "}"
+	}
+	return ""
+}
+
+// 解析 :name、:name<type>、{name}、{name:regex} 和 *name, 静态路径段返回 ok 为 false
+func parseSegment(seg string) (spec paramSpec, ok bool, err error) {
+	if seg == "" {
+		return spec, false, nil
+	}
+	switch seg[0] {
+	case ':':
+		spec.kind, spec.key = param, seg[1:]
+		if i := strings.IndexByte(spec.key, '<'); i >= 0 {
+			if !strings.HasSuffix(spec.key, ">") {
+				return spec, true, errors.New("unterminated type constraint")
+			}
+			spec.key, spec.typ = spec.key[:i], spec.key[i+1:len(spec.key)-1]
+			if _, known := paramTypes[spec.typ]; !known {
+				return spec, true, fmt.Errorf("unknown param type '%s'", spec.typ)
+			}
+		}
+	case '{':
+		if !strings.HasSuffix(seg, "}") {
+			return spec, true, errors.New("wildcard '{...}' must be a whole path segment")
+		}
+		spec.kind, spec.key = param, seg[1:len(seg)-1]
+		if i := strings.IndexByte(spec.key, ':'); i >= 0 {
+			spec.key, spec.regex = spec.key[:i], spec.key[i+1:]
+			if spec.regex == "" {
+				return spec, true, errors.New("empty regex constraint")
+			}
+			if _, err = compileConstraint(spec.regex); err != nil {
+				return spec, true, err
+			}
+		}
+	case '*':
+		// 通配参数可以不命名, 此时不记录参数
+		spec.kind, spec.key = catchAll, seg[1:]
+	default:
+		return spec, false, nil
+	}
+	if spec.key == "" && spec.kind != catchAll {
+		return spec, true, errors.New("wildcard must be named")
+	}
+	if strings.ContainsAny(spec.key, ":*{}<>") {
+		return spec, true, errors.New("only one wildcard per path segment is allowed")
+	}
+	return spec, true, nil
+}
+
+// 返回约束的检查函数, 没有约束时返回 nil
+func (s paramSpec) matcher() func(string) bool {
+	switch {
+	case s.typ != "":
+		return paramTypes[s.typ]
+	case s.regex != "":
+		re, _ := compileConstraint(s.regex)
+		return re.MatchString
+	}
+	return nil
+}
+
+var constraintCache sync.Map // regex -> *regexp.Regexp
+
+// 正则约束需要匹配整个路径段
+func compileConstraint(expr string) (*regexp.Regexp, error) {
+	if re, ok := constraintCache.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, err
+	}
+	constraintCache.Store(expr, re)
+	return re, nil
+}
+
+// UUID RFC 4122 UUID
+type UUID [16]byte
+
+var ErrInvalidUUID = errors.New("gee: invalid uuid")
+
+// String 返回 xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx 格式的小写字符串
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// 解析 xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx 格式, 不区分大小写
+func parseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, ErrInvalidUUID
+	}
+	j := 0
+	for i := 0; i < 36; {
+		if s[i] == '-' {
+			i++
+			continue
+		}
+		hi, ok1 := fromHexChar(s[i])
+		lo, ok2 := fromHexChar(s[i+1])
+		if !ok1 || !ok2 {
+			return u, ErrInvalidUUID
+		}
+		u[j] = hi<<4 | lo
+		i += 2
+		j++
+	}
+	return u, nil
+}
+
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// ParamInt 将路由参数解析为 int
+func (c *Context) ParamInt(key string) (int, error) {
+	value, ok := c.Params.Get(key)
+	if !ok {
+		return 0, fmt.Errorf("gee: param '%s' not found", key)
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("gee: param '%s': %w", key, err)
+	}
+	return n, nil
+}
+
+// ParamUUID 将路由参数解析为 UUID
+func (c *Context) ParamUUID(key string) (UUID, error) {
+	value, ok := c.Params.Get(key)
+	if !ok {
+		return UUID{}, fmt.Errorf("gee: param '%s' not found", key)
+	}
+	u, err := parseUUID(value)
+	if err != nil {
+		return UUID{}, fmt.Errorf("gee: param '%s': %w", key, err)
+	}
+	return u, nil
+}
@@ -0,0 +1,129 @@
+package gee
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParamConstraints(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/users/:id<int>", nil)
+	r.addRoute("GET", "/users/{slug:[a-z]+-[a-z]+}", nil)
+	r.addRoute("GET", "/users/:name", nil)
+	r.addRoute("GET", "/users/:id<int>/posts/{year:[0-9]{4}}", nil)
+	r.addRoute("GET", "/items/:id<uuid>", nil)
+	r.addRoute("GET", "/items/new", nil)
+	r.addRoute("GET", "/tags/{tag}", nil)
+
+	cases := []struct {
+		path, pattern, key, value string
+	}{
+		{"/users/42", "/users/:id<int>", "id", "42"},
+		{"/users/-7", "/users/:id<int>", "id", "-7"},
+		{"/users/tiam-li", "/users/{slug:[a-z]+-[a-z]+}", "slug", "tiam-li"},
+		{"/users/tiam", "/users/:name", "name", "tiam"},
+		{"/users/42/posts/2024", "/users/:id<int>/posts/{year:[0-9]{4}}", "year", "2024"},
+		{"/items/6BA7B810-9DAD-11D1-80B4-00C04FD430C8", "/items/:id<uuid>", "id", "6BA7B810-9DAD-11D1-80B4-00C04FD430C8"},
+		{"/items/new", "/items/new", "", ""},
+		{"/tags/go", "/tags/{tag}", "tag", "go"},
+	}
+	for _, tc := range cases {
+		n, ps := r.getRoute("GET", tc.path)
+		if n == nil || n.pattern != tc.pattern || ps.ByName(tc.key) != tc.value {
+			t.Fatalf("%s: unexpected match %v %v", tc.path, n, ps)
+		}
+	}
+	for _, path := range []string{"/users/42/posts/24", "/users/tiam/posts/2024", "/items/123"} {
+		if n, _ := r.getRoute("GET", path); n != nil {
+			t.Fatalf("%s shouldn't match %s", path, n.pattern)
+		}
+	}
+}
+
+func TestParamConstraintConflict(t *testing.T) {
+	cases := [][]string{
+		{"/p/:id<int>", "/p/:num<int>"},
+		{"/p/{id:[0-9]+}", "/p/{num:[0-9]+}"},
+		{"/p/:id<float>"},
+		{"/p/:id<int"},
+		{"/p/{id:[0-9}"},
+		{"/p/{id}x"},
+		{"/p/{:[0-9]+}"},
+	}
+	for _, patterns := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%v should panic", patterns)
+				}
+			}()
+			r := newRouter()
+			for _, p := range patterns {
+				r.addRoute("GET", p, nil)
+			}
+		}()
+	}
+}
+
+func TestParamAccessors(t *testing.T) {
+	r := New()
+	r.GET("/users/:id<int>", func(c *Context) {
+		id, err := c.ParamInt("id")
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		c.String(http.StatusOK, "user %d", id)
+	})
+	r.GET("/items/:id", func(c *Context) {
+		id, err := c.ParamUUID("id")
+		if err != nil {
+			c.String(http.StatusBadRequest, "%v %v", err, errors.Is(err, ErrInvalidUUID))
+			return
+		}
+		c.String(http.StatusOK, "item %s", id)
+	})
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/42", 200, "user 42"},
+		{"/users/abc", 404, ""},
+		{"/items/6BA7B810-9DAD-11D1-80B4-00C04FD430C8", 200, "item 6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{"/items/6ba7b810", 400, "gee: param 'id': gee: invalid uuid true"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		if w.Code != tc.code || (tc.body != "" && w.Body.String() != tc.body) {
+			t.Fatalf("%s: unexpected response %d %q", tc.path, w.Code, w.Body.String())
+		}
+	}
+
+	c := &Context{}
+	if _, err := c.ParamInt("id"); err == nil {
+		t.Fatal("missing param should fail")
+	}
+}
+
+func TestConstraintURLAndOpenAPI(t *testing.T) {
+	r := New()
+	r.GET("/users/:id<int>/posts/{slug:[a-z-]+}", showUser).Name("post")
+
+	if got, err := r.URL("post", map[string]interface{}{"id": 7, "slug": "hello-world"}); err != nil || got != "/users/7/posts/hello-world" {
+		t.Fatalf("unexpected url %q %v", got, err)
+	}
+	if _, err := r.URL("post", map[string]interface{}{"id": "x", "slug": "hello"}); err == nil {
+		t.Fatal("value violating the constraint should fail")
+	}
+
+	op := r.OpenAPI(OpenAPIConfig{}).Paths["/users/{id}/posts/{slug}"]["get"]
+	if op == nil || len(op.Parameters) != 2 || op.Parameters[0].Schema.Type != "integer" ||
+		op.Parameters[1].Schema.Pattern != "^(?:[a-z-]+)$" {
+		t.Fatalf("unexpected operation %+v", op)
+	}
+}
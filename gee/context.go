@@ -1,47 +1,151 @@
 package gee
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 )
 
+// 调用 Abort 后 index 被置为 abortIndex, 处理链长度必须小于该值
+const abortIndex int = math.MaxInt8 / 2
+
 type H map[string]interface{}
 
 type Context struct {
 	// origin objects
-	W   http.ResponseWriter
-	Req *http.Request
+	W         ResponseWriter // 包装后的 http.ResponseWriter, 记录状态码和响应大小
+	Req       *http.Request
+	writermem responseWriter
 
 	// req info
-	Path   string            // 其实就是 pattern
-	Method string            // 请求方法
-	Params map[string]string // 存储Path传递的参数
+	Path     string // 其实就是 pattern
+	Method   string // 请求方法
+	Params   Params // 存储Path传递的参数
+	fullPath string // 匹配到的路由 pattern, 例如 /users/:id
 
 	// resp info
-	StatusCode int // 响应码
+	StatusCode int           // 响应码
+	sameSite   http.SameSite // SetCookie 使用的 SameSite
 
 	// middleware
 	handlers []HandlerFunc // 存储中间件
 	index    int           // 记录当前执行到第几个中间件
 
+	// 中间件与处理方法之间传递的数据, 由 mu 保护, 可在 handler 启动的协程中使用
+	mu   sync.RWMutex
+	Keys map[string]interface{}
+
+	// 通过 c.Error 记录的错误, 处理链结束后由 Engine.ErrorHandler 统一处理
+	Errors Errors
+
 	// engine pointer
 	engine *Engine
 }
 
 func NewContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		W:      w,
-		Req:    req,
-		Path:   req.URL.Path,
-		Method: req.Method,
-		index:  -1,
+	c := &Context{}
+	c.reset(w, req)
+	return c
+}
+
+// 复用 Context 前清空上一次请求的状态, 保留已分配的切片
+func (c *Context) reset(w http.ResponseWriter, req *http.Request) {
+	c.writermem.reset(w)
+	c.W = &c.writermem
+	c.Req = req
+	c.Path = req.URL.Path
+	c.Method = req.Method
+	c.Params = c.Params[:0]
+	c.fullPath = ""
+	c.StatusCode = 0
+	c.sameSite = http.SameSiteDefaultMode
+	c.handlers = nil
+	c.index = -1
+	c.Errors = c.Errors[:0]
+	c.mu.Lock()
+	c.Keys = nil
+	c.mu.Unlock()
+}
+
+// Copy 返回当前 Context 的副本, 请求结束后 Context 会被放回池中复用,
+// 因此在 handler 返回后仍需使用时(例如新开的协程中), 必须使用副本, 且不能再写响应
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		Req:        c.Req,
+		Path:       c.Path,
+		Method:     c.Method,
+		fullPath:   c.fullPath,
+		StatusCode: c.StatusCode,
+		engine:     c.engine,
+		writermem:  c.writermem,
 	}
+	cp.W = &cp.writermem
+	cp.writermem.before = nil
+	cp.Params = make(Params, len(c.Params))
+	copy(cp.Params, c.Params)
+	cp.Errors = append(Errors(nil), c.Errors...)
+	c.mu.RLock()
+	if c.Keys != nil {
+		cp.Keys = make(map[string]interface{}, len(c.Keys))
+		for k, v := range c.Keys {
+			cp.Keys[k] = v
+		}
+	}
+	c.mu.RUnlock()
+	return cp
+}
+
+// Set 保存键值对, 供后续中间件和处理方法使用
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+	c.mu.Unlock()
+}
+
+// Get 读取 key 对应的值, exists 表示是否存在
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.mu.RLock()
+	value, exists = c.Keys[key]
+	c.mu.RUnlock()
+	return
+}
+
+// MustGet 读取 key 对应的值, 不存在时 panic
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("gee: key \"" + key + "\" does not exist")
+}
+
+// GetString 读取 string 类型的值, 不存在或类型不符时返回空串
+func (c *Context) GetString(key string) (s string) {
+	if value, ok := c.Get(key); ok && value != nil {
+		s, _ = value.(string)
+	}
+	return
+}
+
+// GetInt 读取 int 类型的值, 不存在或类型不符时返回 0
+func (c *Context) GetInt(key string) (i int) {
+	if value, ok := c.Get(key); ok && value != nil {
+		i, _ = value.(int)
+	}
+	return
 }
 
 // 获取传递的参数
 func (c *Context) Param(key string) string {
-	return c.Params[key]
+	return c.Params.ByName(key)
 }
 
 // 获取Form的数据
@@ -49,6 +153,52 @@ func (c *Context) PostForm(key string) string {
 	return c.Req.FormValue(key)
 }
 
+// 直接连接方的地址
+func (c *Context) remoteIP() string {
+	ip, _, err := net.SplitHostPort(strings.TrimSpace(c.Req.RemoteAddr))
+	if err != nil {
+		return c.Req.RemoteAddr
+	}
+	return ip
+}
+
+// 直接连接方是否为可信代理, 只有可信代理设置的 X-Forwarded-* 才可信
+func (c *Context) fromTrustedProxy() bool {
+	ip := net.ParseIP(c.remoteIP())
+	return ip != nil && c.engine != nil && c.engine.isTrustedProxy(ip)
+}
+
+// ClientIP 返回客户端地址, 仅当直接连接方是可信代理时才解析 X-Forwarded-For 和 X-Real-IP
+func (c *Context) ClientIP() string {
+	remoteIP := c.remoteIP()
+	if !c.fromTrustedProxy() {
+		return remoteIP
+	}
+	// X-Forwarded-For: client, proxy1, proxy2 从右往左跳过可信代理
+	if xff := c.Req.Header.Get("X-Forwarded-For"); xff != "" {
+		items := strings.Split(xff, ",")
+		for i := len(items) - 1; i >= 0; i-- {
+			item := strings.TrimSpace(items[i])
+			itemIP := net.ParseIP(item)
+			if itemIP == nil {
+				break
+			}
+			if i == 0 || !c.engine.isTrustedProxy(itemIP) {
+				return item
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(c.Req.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return remoteIP
+}
+
+// FullPath 返回匹配到的路由 pattern, 例如 /users/:id, 未匹配到路由时返回空字符串
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
 // 获取Query的数据
 func (c *Context) Query(key string) string {
 	return c.Req.URL.Query().Get(key)
@@ -63,6 +213,37 @@ func (c *Context) SetHeader(key string, value string) {
 	c.W.Header().Set(key, value)
 }
 
+// SetSameSite 设置之后 SetCookie 使用的 SameSite 属性
+func (c *Context) SetSameSite(sameSite http.SameSite) {
+	c.sameSite = sameSite
+}
+
+// SetCookie 在响应中设置 cookie, value 会进行 URL 编码, maxAge < 0 表示删除 cookie
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(c.W, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		SameSite: c.sameSite,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	})
+}
+
+// Cookie 返回请求中 URL 解码后的 cookie, 不存在时返回 http.ErrNoCookie
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
+}
+
 func (c *Context) String(code int, format string, values ...interface{}) {
 	c.SetHeader("Content-Type", "text/plain")
 	c.Status(code)
@@ -79,16 +260,46 @@ func (c *Context) JSON(code int, obj interface{}) {
 	c.Status(code)
 	encoder := json.NewEncoder(c.W)
 	if err := encoder.Encode(obj); err != nil {
-		http.Error(c.W, err.Error(), 500)
+		c.Error(err).SetType(ErrorTypeRender)
 	}
 }
 
+// HTML 先渲染到缓冲区, 模板执行出错时不会输出不完整的页面
 func (c *Context) HTML(code int, name string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	if err := c.engine.htmlTemplates.ExecuteTemplate(c.W, name, data); err != nil {
-		c.String(500, err.Error())
+	render, err := c.engine.currentHTMLRender()
+	if err != nil {
+		c.Error(err).SetType(ErrorTypeRender)
+		return
 	}
+	var buf bytes.Buffer
+	if err := render.Render(&buf, name, data); err != nil {
+		c.Error(err).SetType(ErrorTypeRender)
+		return
+	}
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.Status(code)
+	c.W.Write(buf.Bytes())
+}
+
+// Abort 阻止执行后续的中间件和处理方法, 当前函数仍会执行完毕
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// AbortWithStatus 写入状态码并终止处理链
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Abort()
+}
+
+// AbortWithStatusJSON 返回 JSON 响应并终止处理链
+func (c *Context) AbortWithStatusJSON(code int, obj interface{}) {
+	c.Abort()
+	c.JSON(code, obj)
 }
 
 // middleware
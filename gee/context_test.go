@@ -0,0 +1,102 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestContextKeys(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	c.Set("user", "geektutu")
+	c.Set("age", 18)
+
+	if c.GetString("user") != "geektutu" || c.GetInt("age") != 18 {
+		t.Fatal("failed to get stored values")
+	}
+	if c.GetString("age") != "" || c.GetInt("user") != 0 {
+		t.Fatal("mismatched types should return zero value")
+	}
+	if _, exists := c.Get("missing"); exists {
+		t.Fatal("missing key shouldn't exist")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("MustGet of missing key should panic")
+			}
+		}()
+		c.MustGet("missing")
+	}()
+
+	cp := c.Copy()
+	c.Set("user", "tiam")
+	if cp.GetString("user") != "geektutu" {
+		t.Fatal("copy should not share keys with the original context")
+	}
+}
+
+func TestContextConcurrentKeys(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Set("n", i)
+			_ = c.GetInt("n")
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestContextPoolReset(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		if _, exists := c.Get("user"); exists {
+			t.Error("keys should be reset between requests")
+		}
+		c.Set("user", c.Query("name"))
+		c.Next()
+	})
+	r.GET("/hello/:name", func(c *Context) {
+		c.String(http.StatusOK, "%s %s", c.MustGet("user"), c.Param("name"))
+	})
+
+	for _, name := range []string{"a", "b"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/hello/"+name+"?name=u"+name, nil))
+		if body := w.Body.String(); body != "u"+name+" "+name {
+			t.Fatalf("unexpected body %q", body)
+		}
+	}
+}
+
+func TestContextAbort(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		if c.Query("token") == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	})
+	called := false
+	r.GET("/private", func(c *Context) {
+		called = true
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/private", nil))
+	if w.Code != http.StatusUnauthorized || called {
+		t.Fatalf("handler shouldn't run after abort, status %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/private?token=1", nil))
+	if w.Code != http.StatusOK || !called {
+		t.Fatalf("handler should run, status %d", w.Code)
+	}
+}
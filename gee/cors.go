@@ -0,0 +1,115 @@
+package gee
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig 跨域资源共享配置
+type CORSConfig struct {
+	AllowOrigins     []string                 // 允许的来源, "*" 表示全部, 支持 https://*.example.com 形式的通配
+	AllowOriginFunc  func(origin string) bool // 自定义来源校验, 优先于 AllowOrigins
+	AllowMethods     []string                 // 预检请求允许的方法, 默认 GET POST PUT PATCH DELETE HEAD
+	AllowHeaders     []string                 // 预检请求允许的请求头, 为空时回显 Access-Control-Request-Headers
+	ExposeHeaders    []string                 // 允许前端读取的响应头
+	AllowCredentials bool                     // 是否允许携带 cookie, 为 true 时不会返回 "*"
+	MaxAge           time.Duration            // 预检结果的缓存时间
+}
+
+var defaultCORSMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead,
+}
+
+// CORS 跨域中间件, 预检请求(OPTIONS + Access-Control-Request-Method)在此直接返回 204,
+// 注册在 Engine 或路由组上时, 对框架自动应答的 OPTIONS 同样生效
+func CORS(conf CORSConfig) HandlerFunc {
+	methods := conf.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(conf.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(conf.ExposeHeaders, ", ")
+	maxAge := ""
+	if conf.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(conf.MaxAge/time.Second), 10)
+	}
+	allowAll := false
+	for _, o := range conf.AllowOrigins {
+		if o == "*" {
+			allowAll = true
+		}
+	}
+
+	return func(c *Context) {
+		origin := c.Req.Header.Get("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		header := c.W.Header()
+		header.Add("Vary", "Origin")
+		preflight := c.Method == http.MethodOptions && c.Req.Header.Get("Access-Control-Request-Method") != ""
+		if !conf.allowOrigin(origin, allowAll) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAll && !conf.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if conf.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", allowHeaders)
+			} else if reqHeaders := c.Req.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", reqHeaders)
+			}
+			if maxAge != "" {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		if exposeHeaders != "" {
+			header.Set("Access-Control-Expose-Headers", exposeHeaders)
+		}
+		c.Next()
+	}
+}
+
+func (conf *CORSConfig) allowOrigin(origin string, allowAll bool) bool {
+	if conf.AllowOriginFunc != nil {
+		return conf.AllowOriginFunc(origin)
+	}
+	if allowAll {
+		return true
+	}
+	for _, allowed := range conf.AllowOrigins {
+		if allowed == origin {
+			return true
+		}
+		// https://*.example.com 匹配任意子域名
+		if i := strings.Index(allowed, "*"); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
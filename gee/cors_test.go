@@ -0,0 +1,53 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	r := New()
+	api := r.Group("/api")
+	api.Use(CORS(CORSConfig{
+		AllowOrigins:     []string{"https://*.example.com"},
+		AllowHeaders:     []string{"Content-Type"},
+		ExposeHeaders:    []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}))
+	api.POST("/users", func(c *Context) { c.String(http.StatusCreated, "ok") })
+
+	// 没有注册 OPTIONS 路由, 由框架自动应答, 组中间件同样生效
+	req := httptest.NewRequest("OPTIONS", "/api/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight status = %d", w.Code)
+	}
+	h := w.Header()
+	if h.Get("Access-Control-Allow-Origin") != "https://app.example.com" || h.Get("Access-Control-Allow-Credentials") != "true" ||
+		h.Get("Access-Control-Allow-Headers") != "Content-Type" || h.Get("Access-Control-Max-Age") != "3600" {
+		t.Fatalf("unexpected preflight headers %v", h)
+	}
+
+	req = httptest.NewRequest("OPTIONS", "/api/users", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("disallowed origin status = %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || w.Header().Get("Access-Control-Expose-Headers") != "X-Total" {
+		t.Fatalf("actual request: %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,150 @@
+package gee
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CSRFKey CSRF token 在 Context 中的 key
+const CSRFKey = "gee.csrf_token"
+
+// CSRFConfig 双重提交 cookie 方式的 CSRF 防护配置, 零值即为默认配置
+type CSRFConfig struct {
+	CookieName   string        // 保存 token 的 cookie, 默认 _csrf
+	HeaderName   string        // 提交 token 的请求头, 默认 X-CSRF-Token
+	FormField    string        // 提交 token 的表单字段, 默认 _csrf
+	Secret       []byte        // 设置后 cookie 中的 token 带 HMAC 签名, 防止子域名写入伪造的 cookie
+	Path         string        // cookie 的 Path, 默认 /
+	Domain       string        // cookie 的 Domain
+	MaxAge       time.Duration // cookie 有效期, 默认 12 小时
+	Secure       bool          // cookie 仅通过 HTTPS 发送
+	SameSite     http.SameSite // 默认 SameSiteLaxMode
+	ErrorHandler HandlerFunc   // 校验失败时调用, 默认返回 403
+}
+
+// CSRF 对 GET、HEAD、OPTIONS、TRACE 之外的请求校验请求头或表单中的 token 与 cookie 是否一致,
+// 页面中通过 CSRFToken(c) 获取 token 放入表单或请求头, 前端脚本也可以直接提交 cookie 的值
+func CSRF(conf CSRFConfig) HandlerFunc {
+	if conf.CookieName == "" {
+		conf.CookieName = "_csrf"
+	}
+	if conf.HeaderName == "" {
+		conf.HeaderName = "X-CSRF-Token"
+	}
+	if conf.FormField == "" {
+		conf.FormField = "_csrf"
+	}
+	if conf.Path == "" {
+		conf.Path = "/"
+	}
+	if conf.MaxAge == 0 {
+		conf.MaxAge = 12 * time.Hour
+	}
+	if conf.SameSite == 0 {
+		conf.SameSite = http.SameSiteLaxMode
+	}
+	if conf.ErrorHandler == nil {
+		conf.ErrorHandler = func(c *Context) {
+			c.AbortWithStatusJSON(http.StatusForbidden, H{"error": "invalid csrf token"})
+		}
+	}
+
+	return func(c *Context) {
+		token := ""
+		if cookie, err := c.Req.Cookie(conf.CookieName); err == nil {
+			token = conf.verify(cookie.Value)
+		}
+		if token == "" {
+			token = newCSRFToken()
+			// 前端脚本需要读取 cookie 放入请求头, 因此不设置 HttpOnly
+			http.SetCookie(c.W, &http.Cookie{
+				Name:     conf.CookieName,
+				Value:    conf.sign(token),
+				Path:     conf.Path,
+				Domain:   conf.Domain,
+				MaxAge:   int(conf.MaxAge / time.Second),
+				Secure:   conf.Secure,
+				SameSite: conf.SameSite,
+			})
+		}
+		c.Set(CSRFKey, token)
+
+		switch c.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			c.Next()
+			return
+		}
+		submitted := c.Req.Header.Get(conf.HeaderName)
+		if submitted == "" {
+			submitted = c.csrfFormValue(conf.FormField)
+		}
+		if len(conf.Secret) > 0 && strings.IndexByte(submitted, '.') >= 0 {
+			// 前端脚本直接提交 cookie 中带签名的值, token 本身不含 .
+			submitted = conf.verify(submitted)
+		}
+		if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+			conf.ErrorHandler(c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CSRFToken 返回当前请求的 CSRF token, 未使用 CSRF 中间件时返回空字符串
+func CSRFToken(c *Context) string {
+	return c.GetString(CSRFKey)
+}
+
+// 按请求的 Content-Type 解析表单, 与 BindForm 使用相同的内存上限
+func (c *Context) csrfFormValue(field string) string {
+	if strings.HasPrefix(c.Req.Header.Get("Content-Type"), MIMEMultipartPOSTForm) {
+		if err := c.Req.ParseMultipartForm(c.maxMultipartMemory()); err != nil {
+			return ""
+		}
+	}
+	return c.Req.PostFormValue(field)
+}
+
+func newCSRFToken() string {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("gee: failed to generate csrf token: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// token.signature
+func (conf *CSRFConfig) sign(token string) string {
+	if len(conf.Secret) == 0 {
+		return token
+	}
+	mac := hmac.New(sha256.New, conf.Secret)
+	mac.Write([]byte(token))
+	return token + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// 校验 cookie 中的签名, 返回其中的 token, 无效时返回空字符串
+func (conf *CSRFConfig) verify(value string) string {
+	token := value
+	if len(conf.Secret) > 0 {
+		i := strings.LastIndexByte(value, '.')
+		if i < 0 {
+			return ""
+		}
+		token = value[:i]
+		if !hmac.Equal([]byte(conf.sign(token)), []byte(value)) {
+			return ""
+		}
+	}
+	if len(token) != base64.RawURLEncoding.EncodedLen(32) {
+		return ""
+	}
+	return token
+}
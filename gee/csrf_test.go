@@ -0,0 +1,64 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	r := New()
+	r.Use(CSRF(CSRFConfig{Secret: []byte("secret")}))
+	r.GET("/form", func(c *Context) { c.String(http.StatusOK, CSRFToken(c)) })
+	r.POST("/form", func(c *Context) { c.String(http.StatusOK, "saved") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/form", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "_csrf" || cookies[0].HttpOnly {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+	token := w.Body.String()
+	if token == "" || !strings.HasPrefix(cookies[0].Value, token+".") {
+		t.Fatalf("token %q doesn't match cookie %q", token, cookies[0].Value)
+	}
+
+	post := func(header, field string, cookie *http.Cookie) int {
+		req := httptest.NewRequest("POST", "/form", strings.NewReader(url.Values{"_csrf": {field}}.Encode()))
+		req.Header.Set("Content-Type", MIMEPOSTForm)
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := post(token, "", cookies[0]); code != http.StatusOK {
+		t.Fatalf("header token rejected: %d", code)
+	}
+	if code := post("", token, cookies[0]); code != http.StatusOK {
+		t.Fatalf("form token rejected: %d", code)
+	}
+	// 前端脚本将 cookie 的值复制到请求头
+	if code := post(cookies[0].Value, "", cookies[0]); code != http.StatusOK {
+		t.Fatalf("cookie value rejected: %d", code)
+	}
+	if code := post(token+".forged", "", cookies[0]); code != http.StatusForbidden {
+		t.Fatalf("forged signature accepted: %d", code)
+	}
+	if code := post("", token, nil); code != http.StatusForbidden {
+		t.Fatalf("missing cookie accepted: %d", code)
+	}
+	if code := post("wrong", "", cookies[0]); code != http.StatusForbidden {
+		t.Fatalf("wrong token accepted: %d", code)
+	}
+	// 未签名的 cookie 视为无效
+	if code := post(token, "", &http.Cookie{Name: "_csrf", Value: token}); code != http.StatusForbidden {
+		t.Fatalf("unsigned cookie accepted: %d", code)
+	}
+}
@@ -0,0 +1,225 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrorType 错误的分类, 可以按位组合
+type ErrorType uint64
+
+const (
+	ErrorTypePrivate ErrorType = 1 << iota // 内部错误, 错误信息不返回给客户端, 默认类型
+	ErrorTypePublic                        // 错误信息可以返回给客户端
+	ErrorTypeBind                          // 解析或校验请求失败, 默认状态码 400
+	ErrorTypeRender                        // 渲染响应失败
+
+	ErrorTypeAny ErrorType = 1<<64 - 1
+)
+
+// MIMEProblemJSON RFC 7807 problem details 的 Content-Type
+const MIMEProblemJSON = "application/problem+json"
+
+// Error 通过 c.Error 记录的错误
+type Error struct {
+	Err    error
+	Type   ErrorType
+	Status int         // 响应的状态码, 为 0 时由 ErrorHandler 根据类型决定
+	Meta   interface{} // 附加信息, 例如出错的字段
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// SetType 设置错误类型
+func (e *Error) SetType(t ErrorType) *Error {
+	e.Type = t
+	return e
+}
+
+// SetStatus 设置响应的状态码
+func (e *Error) SetStatus(code int) *Error {
+	e.Status = code
+	return e
+}
+
+// SetMeta 设置附加信息
+func (e *Error) SetMeta(meta interface{}) *Error {
+	e.Meta = meta
+	return e
+}
+
+// IsType 判断错误是否属于 flags 中的任意一种类型
+func (e *Error) IsType(flags ErrorType) bool {
+	return e.Type&flags > 0
+}
+
+// Errors 一次请求中记录的所有错误
+type Errors []*Error
+
+// ByType 返回属于 flags 的错误
+func (errs Errors) ByType(flags ErrorType) Errors {
+	if flags == ErrorTypeAny {
+		return errs
+	}
+	var result Errors
+	for _, e := range errs {
+		if e.IsType(flags) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Last 返回最后一个错误, 没有时返回 nil
+func (errs Errors) Last() *Error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[len(errs)-1]
+}
+
+// Errors 返回所有错误信息
+func (errs Errors) Errors() []string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return msgs
+}
+
+func (errs Errors) String() string {
+	return strings.Join(errs.Errors(), "; ")
+}
+
+// Error 记录错误, 整个处理链结束后由 Engine.ErrorHandler 统一生成响应,
+// 中间件也可以在 c.Next() 之后自行处理 c.Errors.
+// 校验失败的 ValidationErrors 默认为 ErrorTypeBind, 其他错误为 ErrorTypePrivate
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("gee: c.Error(nil)")
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Err: err, Type: ErrorTypePrivate}
+		var verrs ValidationErrors
+		if errors.As(err, &verrs) {
+			e.Type = ErrorTypeBind
+		}
+	}
+	c.Errors = append(c.Errors, e)
+	return e
+}
+
+// AbortWithError 记录错误并终止处理链, 由 ErrorHandler 以 code 生成响应
+func (c *Context) AbortWithError(code int, err error) *Error {
+	c.Abort()
+	return c.Error(err).SetStatus(code)
+}
+
+// 整个处理链结束后调用一次 ErrorHandler, 中间件已经写出响应时不再处理
+func (c *Context) handleErrors() {
+	if len(c.Errors) == 0 || c.W.Written() || c.engine == nil || c.engine.ErrorHandler == nil {
+		return
+	}
+	c.engine.ErrorHandler(c)
+	c.W.WriteHeaderNow()
+}
+
+// Problem RFC 7807 problem details
+type Problem struct {
+	Type     string      `json:"type,omitempty"` // 问题类型的 URI, 默认为 about:blank
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Errors   interface{} `json:"errors,omitempty"` // 扩展字段, 例如字段校验错误
+}
+
+// Problem 以 application/problem+json 返回错误, Title 和 Instance 为空时使用状态码描述和请求路径
+func (c *Context) Problem(p Problem) {
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = c.Req.URL.Path
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		// 扩展字段无法编码时去掉扩展字段
+		p.Errors = nil
+		data, _ = json.Marshal(p)
+	}
+	c.SetHeader("Content-Type", MIMEProblemJSON)
+	c.Status(p.Status)
+	if c.Method != http.MethodHead {
+		c.W.Write(data)
+	}
+}
+
+// DefaultErrorHandler 按最后一个错误返回 problem details:
+// 状态码依次取 Error.Status、handler 已设置的 4xx/5xx 状态码, 解析错误为 400, 其余为 500;
+// 只有 ErrorTypePublic 和 ErrorTypeBind 的错误信息会返回给客户端
+func DefaultErrorHandler(c *Context) {
+	e := c.Errors.Last()
+	status := e.Status
+	if status == 0 {
+		switch {
+		case c.W.Status() >= 400:
+			status = c.W.Status()
+		case e.IsType(ErrorTypeBind):
+			status = http.StatusBadRequest
+		default:
+			status = http.StatusInternalServerError
+		}
+	}
+	p := Problem{Status: status}
+	if e.IsType(ErrorTypePublic | ErrorTypeBind) {
+		p.Detail = e.Error()
+		var verrs ValidationErrors
+		if errors.As(e.Err, &verrs) {
+			p.Errors = verrs
+		} else if e.Meta != nil {
+			p.Errors = e.Meta
+		}
+	}
+	c.Problem(p)
+}
+
+// NoRoute 设置没有匹配到路由时的处理方法, 会先执行 Engine 的全局中间件, 默认返回 404
+func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
+	engine.noRoute = handlers
+	engine.rebuild404Handlers()
+}
+
+// NoMethod 设置 path 存在但请求方法不匹配时的处理方法, 会先执行匹配路由所在路由组的中间件,
+// 调用前已设置 Allow 响应头, 默认返回 405
+func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
+	engine.noMethod = handlers
+}
+
+func (engine *Engine) rebuild404Handlers() {
+	handlers := engine.noRoute
+	if len(handlers) == 0 {
+		handlers = []HandlerFunc{defaultNoRoute}
+	}
+	engine.allNoRoute = engine.combineHandlers(handlers)
+}
+
+func defaultNoRoute(c *Context) {
+	c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+}
+
+func defaultNoMethod(c *Context) {
+	c.String(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s %s\n", c.Method, c.Path)
+}
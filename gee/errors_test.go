@@ -0,0 +1,154 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorHandler(t *testing.T) {
+	r := New()
+	var seen []int
+	r.Use(func(c *Context) {
+		c.Next()
+		seen = append(seen, len(c.Errors))
+	})
+	r.GET("/private", func(c *Context) {
+		c.Error(errors.New("db password is wrong"))
+	})
+	r.GET("/public", func(c *Context) {
+		c.AbortWithError(http.StatusConflict, errors.New("name is taken")).SetType(ErrorTypePublic)
+	})
+	r.GET("/bind", func(c *Context) {
+		var q struct {
+			Name string `form:"name" binding:"required"`
+		}
+		if err := c.Bind(&q); err != nil {
+			c.Error(err)
+			return
+		}
+	})
+	r.GET("/status", func(c *Context) {
+		c.Status(http.StatusNotFound)
+		c.Error(errors.New("user not found")).SetType(ErrorTypePublic)
+	})
+	r.GET("/written", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+		c.Error(errors.New("late"))
+	})
+	r.GET("/render", func(c *Context) {
+		c.HTML(http.StatusOK, "missing.tmpl", nil)
+	})
+
+	cases := []struct {
+		path   string
+		status int
+		detail string
+	}{
+		{"/private", http.StatusInternalServerError, ""},
+		{"/public", http.StatusConflict, "name is taken"},
+		{"/bind", http.StatusBadRequest, "Name is required"},
+		{"/status", http.StatusNotFound, "user not found"},
+		{"/render", http.StatusInternalServerError, ""},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		var p Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s: %v %q", tc.path, err, w.Body.String())
+		}
+		if w.Code != tc.status || w.Header().Get("Content-Type") != MIMEProblemJSON ||
+			p.Status != tc.status || p.Title != http.StatusText(tc.status) || p.Instance != tc.path {
+			t.Fatalf("%s: unexpected response %d %q", tc.path, w.Code, w.Body.String())
+		}
+		if !strings.Contains(p.Detail, tc.detail) || (tc.detail == "" && p.Detail != "") {
+			t.Fatalf("%s: unexpected detail %q", tc.path, p.Detail)
+		}
+		if tc.path == "/bind" && p.Errors == nil {
+			t.Fatalf("validation errors should be included: %q", w.Body.String())
+		}
+		// 外层中间件返回时错误还没有处理
+		if seen[len(seen)-1] == 0 {
+			t.Fatalf("%s: middleware should see c.Errors", tc.path)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/written", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("written response should not be replaced: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestCustomErrorHandler(t *testing.T) {
+	r := New()
+	r.ErrorHandler = func(c *Context) {
+		c.JSON(http.StatusTeapot, H{"errors": c.Errors.ByType(ErrorTypePublic).Errors()})
+	}
+	r.GET("/", func(c *Context) {
+		c.Error(errors.New("secret"))
+		c.Error(errors.New("visible")).SetType(ErrorTypePublic)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusTeapot || w.Body.String() != "{\"errors\":[\"visible\"]}\n" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestMiddlewareHandlesErrors(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		c.Next()
+		if len(c.Errors) > 0 {
+			c.JSON(http.StatusBadGateway, H{"mine": c.Errors.Last().Error()})
+		}
+	})
+	r.GET("/", func(c *Context) {
+		c.Error(errors.New("boom"))
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusBadGateway || w.Body.String() != "{\"mine\":\"boom\"}\n" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestNoRouteNoMethod(t *testing.T) {
+	r := New()
+	r.NoRoute(func(c *Context) {
+		c.Problem(Problem{Status: http.StatusNotFound, Detail: "no route for " + c.Path})
+	})
+	// NoRoute 之后注册的全局中间件同样生效
+	r.Use(func(c *Context) {
+		c.SetHeader("X-Global", "1")
+		c.Next()
+	})
+	api := r.Group("/api")
+	api.Use(func(c *Context) {
+		c.SetHeader("X-Group", "1")
+		c.Next()
+	})
+	api.GET("/users", func(c *Context) {})
+	r.NoMethod(func(c *Context) {
+		c.JSON(http.StatusMethodNotAllowed, H{"allow": c.W.Header().Get("Allow")})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("X-Global") != "1" ||
+		w.Header().Get("Content-Type") != MIMEProblemJSON || !strings.Contains(w.Body.String(), "no route for /missing") {
+		t.Fatalf("unexpected 404 response %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/api/users", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("X-Group") != "1" ||
+		w.Body.String() != "{\"allow\":\"GET, HEAD, OPTIONS\"}\n" {
+		t.Fatalf("unexpected 405 response %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+}
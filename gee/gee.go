@@ -2,8 +2,11 @@ package gee
 
 import (
 	"html/template"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 )
 
 // HandlerFunc 提供给框架用户的，用来定义路由映射的处理方法
@@ -11,55 +14,144 @@ type HandlerFunc func(c *Context)
 
 type Engine struct {
 	*RouteGroup
-	router        *router
-	groups        []*RouteGroup      // store all groups
-	htmlTemplates *template.Template // for html render	将所有的模板加载进内存
-	funcMap       template.FuncMap   // for html render	所有的自定义模板渲染函数
+	router     *router
+	groups     []*RouteGroup    // store all groups
+	htmlRender HTMLRender       // for html render	将所有的模板加载进内存
+	htmlLoader htmlLoader       // for html render	重新加载模板
+	funcMap    template.FuncMap // for html render	所有的自定义模板渲染函数
+	pool       sync.Pool        // 复用 Context, 减少每次请求的内存分配
+
+	namedRoutes map[string]*Route // 命名路由, 用于 URL 反向生成路径
+
+	// ErrorHandler 处理链结束后处理 c.Errors, 响应已经写出时不会调用, 默认为 DefaultErrorHandler
+	ErrorHandler HandlerFunc
+	noRoute      []HandlerFunc
+	allNoRoute   []HandlerFunc // 加上全局中间件后的 noRoute
+	noMethod     []HandlerFunc
+
+	secureJSONPrefix string // SecureJSON 在 JSON 数组前添加的前缀
+
+	// 解析 multipart 表单时保存在内存中的最大字节数, 超出部分写入临时文件
+	MaxMultipartMemory int64
+
+	// 每次渲染 HTML 前重新从磁盘加载模板, 仅用于开发调试
+	HTMLAutoReload bool
+
+	// 没有匹配的路由但增删末尾的 / 后可以匹配时重定向, GET 返回 301, 其他方法返回 308, 默认开启
+	RedirectTrailingSlash bool
+	// 没有匹配的路由时清理路径中的 .. 和重复的 /, 并忽略大小写查找, 找到时重定向, 默认关闭
+	RedirectFixedPath bool
+	// 按 URL.RawPath 匹配路由, 参数中转义的 / (%2F) 不会被当作路径分隔符, 默认关闭
+	UseRawPath bool
+	// UseRawPath 开启时对参数值进行反转义, 默认开启
+	UnescapePathValues bool
+
+	trustedCIDRs []*net.IPNet // 可信代理, 只有来自这些地址的 X-Forwarded-For 才会被采用
+
+	// http.Server 配置, 在调用 Run 系列方法前设置, 0 表示不限制
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	mu           sync.Mutex
+	servers      []*http.Server // Run 系列方法启动的所有 server, Shutdown 时统一关闭
+	onShutdown   []func()
+	shuttingDown bool          // 已调用 Shutdown, 之后的 Run 系列方法直接返回 http.ErrServerClosed
+	shutdownDone chan struct{} // Shutdown 等待处理中的请求结束后关闭
 }
 
 func New() *Engine {
-	engine := &Engine{router: newRouter()}
+	engine := &Engine{
+		router:             newRouter(),
+		secureJSONPrefix:   "while(1);",
+		MaxMultipartMemory: defaultMultipartMemory,
+		ErrorHandler:       DefaultErrorHandler,
+
+		RedirectTrailingSlash: true,
+		UnescapePathValues:    true,
+	}
 	engine.RouteGroup = &RouteGroup{engine: engine}
 	engine.groups = []*RouteGroup{engine.RouteGroup}
+	engine.rebuild404Handlers()
+	engine.pool.New = func() interface{} {
+		return engine.allocateContext()
+	}
 	return engine
 }
 
-func (engine *Engine) addRoute(method string, pattern string, handler HandlerFunc) {
-	engine.router.addRoute(method, pattern, handler)
-}
-
-// pattern 其实就是路径
-func (engine *Engine) GET(pattern string, handler HandlerFunc) {
-	engine.addRoute("GET", pattern, handler)
+func (engine *Engine) allocateContext() *Context {
+	return &Context{
+		Params: make(Params, 0, engine.router.maxParams),
+		engine: engine,
+	}
 }
 
-func (engine *Engine) POST(pattern string, handler HandlerFunc) {
-	engine.addRoute("POST", pattern, handler)
+// SetTrustedProxies 设置可信代理的 IP 或 CIDR, 用于 Context.ClientIP 解析真实客户端地址
+func (engine *Engine) SetTrustedProxies(proxies []string) error {
+	cidrs := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return &net.ParseError{Type: "IP address", Text: proxy}
+			}
+			if ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	engine.trustedCIDRs = cidrs
+	return nil
 }
 
-func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
-	engine.funcMap = funcMap
+func (engine *Engine) isTrustedProxy(ip net.IP) bool {
+	for _, cidr := range engine.trustedCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
 }
 
-func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+// SetSecureJSONPrefix 设置 Context.SecureJSON 使用的前缀
+func (engine *Engine) SetSecureJSONPrefix(prefix string) {
+	engine.secureJSONPrefix = prefix
 }
 
 // 实现 Handler 接口
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	// 先执行中间件
-	var middlewares []HandlerFunc
-	for _, group := range engine.groups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middlewares...)
-		}
-	}
-	c := NewContext(w, req)
-	c.handlers = middlewares
-	c.engine = engine
+	c := engine.pool.Get().(*Context)
+	c.reset(w, req)
 	engine.router.handle(c)
+	// 处理方法只设置了状态码而没有写响应体时, 在这里发送响应头
+	c.W.WriteHeaderNow()
+	engine.pool.Put(c)
 }
 
-func (engine *Engine) Run(addr string) (err error) {
-	return http.ListenAndServe(addr, engine)
+// CreateTestContext 创建属于 engine 的 Context, 配合 HandleContext 不经过路由单独测试 handler 和中间件
+func (engine *Engine) CreateTestContext(w http.ResponseWriter, req *http.Request) *Context {
+	c := engine.allocateContext()
+	c.reset(w, req)
+	return c
+}
+
+// HandleContext 依次执行 handlers, 结束后处理 c.Errors 并发送响应头, 与路由匹配后的处理过程相同
+func (engine *Engine) HandleContext(c *Context, handlers ...HandlerFunc) {
+	if len(handlers) >= abortIndex {
+		panic("gee: too many handlers")
+	}
+	c.handlers = handlers
+	c.index = -1
+	c.Next()
+	c.handleErrors()
+	c.W.WriteHeaderNow()
 }
@@ -0,0 +1,341 @@
+// Package geetest 测试 gee 应用的辅助工具:
+// Client 构造请求并在多次请求之间保存 cookie, Response 断言状态码、响应头和 JSON 响应体,
+// CreateTestContext 不经过路由单独测试 handler 或中间件
+package geetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"qitian/gee"
+)
+
+// Client 向 handler 发送请求, 通常为 *gee.Engine, 需要通过 New 创建
+type Client struct {
+	Handler http.Handler
+	BaseURL string         // 请求的 scheme 和 host, 默认 http://example.com, 决定哪些 cookie 会被发送
+	Jar     http.CookieJar // 保存响应中的 cookie 并在之后的请求中发送, 为 nil 时不处理 cookie
+	Header  http.Header    // 每个请求都会带上的请求头
+
+	t testing.TB
+}
+
+// New 创建带有 cookie jar 的 Client
+func New(t testing.TB, handler http.Handler) *Client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Client{Handler: handler, BaseURL: "http://example.com", Jar: jar, Header: http.Header{}, t: t}
+}
+
+func (c *Client) GET(path string) *Request     { return c.NewRequest(http.MethodGet, path) }
+func (c *Client) POST(path string) *Request    { return c.NewRequest(http.MethodPost, path) }
+func (c *Client) PUT(path string) *Request     { return c.NewRequest(http.MethodPut, path) }
+func (c *Client) PATCH(path string) *Request   { return c.NewRequest(http.MethodPatch, path) }
+func (c *Client) DELETE(path string) *Request  { return c.NewRequest(http.MethodDelete, path) }
+func (c *Client) HEAD(path string) *Request    { return c.NewRequest(http.MethodHead, path) }
+func (c *Client) OPTIONS(path string) *Request { return c.NewRequest(http.MethodOptions, path) }
+
+// NewRequest 开始构造请求, 调用 Do 发送
+func (c *Client) NewRequest(method, path string) *Request {
+	c.mustNew()
+	header := http.Header{}
+	for k, v := range c.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	return &Request{client: c, method: method, path: path, header: header, query: url.Values{}}
+}
+
+// Cookie 返回 jar 中当前会随 path 发送的 cookie, 不存在时返回 nil
+func (c *Client) Cookie(path, name string) *http.Cookie {
+	c.mustNew()
+	if c.Jar == nil {
+		return nil
+	}
+	u, err := url.Parse(c.BaseURL + path)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	for _, cookie := range c.Jar.Cookies(u) {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// 直接构造的 Client 没有 testing.TB, 无法报告失败
+func (c *Client) mustNew() {
+	if c.t == nil {
+		panic("geetest: Client must be created by geetest.New")
+	}
+}
+
+// Request 链式构造的请求
+type Request struct {
+	client      *Client
+	method      string
+	path        string
+	header      http.Header
+	query       url.Values
+	cookies     []*http.Cookie
+	body        io.Reader
+	remoteAddr  string
+	err         error
+	contentType string
+}
+
+// Header 设置请求头
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// Query 添加 query 参数, 与 path 中已有的参数合并
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// Cookie 只为本次请求添加 cookie
+func (r *Request) Cookie(name, value string) *Request {
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	return r
+}
+
+// BasicAuth 设置 Authorization: Basic
+func (r *Request) BasicAuth(username, password string) *Request {
+	req := http.Request{Header: http.Header{}}
+	req.SetBasicAuth(username, password)
+	return r.Header("Authorization", req.Header.Get("Authorization"))
+}
+
+// BearerToken 设置 Authorization: Bearer
+func (r *Request) BearerToken(token string) *Request {
+	return r.Header("Authorization", "Bearer "+token)
+}
+
+// RemoteAddr 设置客户端地址, 默认 192.0.2.1:1234
+func (r *Request) RemoteAddr(addr string) *Request {
+	r.remoteAddr = addr
+	return r
+}
+
+// Body 设置原始请求体
+func (r *Request) Body(contentType string, body io.Reader) *Request {
+	r.contentType, r.body = contentType, body
+	return r
+}
+
+// JSON 将 obj 编码为 JSON 请求体
+func (r *Request) JSON(obj interface{}) *Request {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	return r.Body(gee.MIMEJSON, bytes.NewReader(data))
+}
+
+// Form 设置 application/x-www-form-urlencoded 请求体
+func (r *Request) Form(values url.Values) *Request {
+	return r.Body("application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+}
+
+// Build 返回构造好的 *http.Request, 包含 jar 中的 cookie
+func (r *Request) Build() *http.Request {
+	t := r.client.t
+	t.Helper()
+	if r.err != nil {
+		t.Fatalf("geetest: %s %s: %v", r.method, r.path, r.err)
+	}
+	req := httptest.NewRequest(r.method, r.client.BaseURL+r.path, r.body)
+	if len(r.query) > 0 {
+		q := req.URL.Query()
+		for k, vs := range r.query {
+			q[k] = append(q[k], vs...)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if r.remoteAddr != "" {
+		req.RemoteAddr = r.remoteAddr
+	}
+	if r.client.Jar != nil {
+		for _, cookie := range r.client.Jar.Cookies(req.URL) {
+			req.AddCookie(cookie)
+		}
+	}
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+// Do 发送请求, 并将响应中的 cookie 保存到 jar
+func (r *Request) Do() *Response {
+	r.client.t.Helper()
+	req := r.Build()
+	w := httptest.NewRecorder()
+	r.client.Handler.ServeHTTP(w, req)
+	if r.client.Jar != nil {
+		r.client.Jar.SetCookies(req.URL, w.Result().Cookies())
+	}
+	return &Response{ResponseRecorder: w, t: r.client.t}
+}
+
+// Response 请求的响应, 断言失败时调用 t.Errorf, 可以链式调用
+type Response struct {
+	*httptest.ResponseRecorder
+	t testing.TB
+}
+
+// Status 断言状态码
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	if r.Code != code {
+		r.t.Errorf("geetest: status = %d, want %d; body: %s", r.Code, code, r.ResponseRecorder.Body.String())
+	}
+	return r
+}
+
+// Header 断言响应头的值
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+	if got := r.ResponseRecorder.Header().Get(key); got != value {
+		r.t.Errorf("geetest: header %s = %q, want %q", key, got, value)
+	}
+	return r
+}
+
+// HeaderContains 断言响应头包含 substr, 例如 Content-Type 中的 MIME 类型
+func (r *Response) HeaderContains(key, substr string) *Response {
+	r.t.Helper()
+	if got := r.ResponseRecorder.Header().Get(key); !strings.Contains(got, substr) {
+		r.t.Errorf("geetest: header %s = %q, want it to contain %q", key, got, substr)
+	}
+	return r
+}
+
+// Body 断言响应体
+func (r *Response) Body(body string) *Response {
+	r.t.Helper()
+	if got := r.ResponseRecorder.Body.String(); got != body {
+		r.t.Errorf("geetest: body = %q, want %q", got, body)
+	}
+	return r
+}
+
+// BodyContains 断言响应体包含 substr
+func (r *Response) BodyContains(substr string) *Response {
+	r.t.Helper()
+	if got := r.ResponseRecorder.Body.String(); !strings.Contains(got, substr) {
+		r.t.Errorf("geetest: body = %q, want it to contain %q", got, substr)
+	}
+	return r
+}
+
+// JSON 断言响应体与 expected 编码后的 JSON 等价, 忽略字段顺序和空白
+func (r *Response) JSON(expected interface{}) *Response {
+	r.t.Helper()
+	want, err := normalizeJSON(expected)
+	if err != nil {
+		r.t.Fatalf("geetest: encode expected json: %v", err)
+	}
+	var got interface{}
+	if err = json.Unmarshal(r.ResponseRecorder.Body.Bytes(), &got); err != nil {
+		r.t.Errorf("geetest: body is not json: %v; body: %s", err, r.ResponseRecorder.Body.String())
+		return r
+	}
+	if !reflect.DeepEqual(got, want) {
+		r.t.Errorf("geetest: json body = %s, want %s", compactJSON(got), compactJSON(want))
+	}
+	return r
+}
+
+// DecodeJSON 将 JSON 响应体解码到 obj, 失败时终止测试
+func (r *Response) DecodeJSON(obj interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.ResponseRecorder.Body.Bytes(), obj); err != nil {
+		r.t.Fatalf("geetest: decode json body: %v; body: %s", err, r.ResponseRecorder.Body.String())
+	}
+	return r
+}
+
+// Cookie 返回响应设置的 cookie, 不存在时返回 nil
+func (r *Response) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// 通过编码再解码得到与 json.Unmarshal 结果可比较的值
+func normalizeJSON(v interface{}) (interface{}, error) {
+	var data []byte
+	switch v := v.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	var normalized interface{}
+	err := json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// TestContext 不经过路由的 Context, 用于单独测试 handler 或中间件
+type TestContext struct {
+	*gee.Context
+	Engine *gee.Engine
+
+	recorder *httptest.ResponseRecorder
+	t        testing.TB
+}
+
+// CreateTestContext 创建绑定到新 Engine 的 Context, req 为 nil 时使用 GET /,
+// 在 Run 之前可以设置 Params、Keys 等模拟路由和前置中间件的结果
+func CreateTestContext(t testing.TB, req *http.Request) *TestContext {
+	if req == nil {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+	}
+	engine := gee.New()
+	w := httptest.NewRecorder()
+	return &TestContext{Context: engine.CreateTestContext(w, req), Engine: engine, recorder: w, t: t}
+}
+
+// Run 依次执行 handlers, 例如 Run(middleware, handler), 返回写出的响应
+func (tc *TestContext) Run(handlers ...gee.HandlerFunc) *Response {
+	tc.Engine.HandleContext(tc.Context, handlers...)
+	return &Response{ResponseRecorder: tc.recorder, t: tc.t}
+}
@@ -0,0 +1,121 @@
+package geetest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"qitian/gee"
+)
+
+// fakeTB 记录断言失败而不是让测试失败, 用于测试断言本身
+type fakeTB struct {
+	testing.TB
+	failures []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Fatalf(format string, args ...interface{}) {
+	f.Errorf(format, args...)
+}
+
+func (f *fakeTB) Fatal(args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprint(args...))
+}
+
+func TestClient(t *testing.T) {
+	r := gee.New()
+	r.POST("/login", func(c *gee.Context) {
+		var form struct {
+			Name string `form:"name" binding:"required"`
+		}
+		if err := c.Bind(&form); err != nil {
+			c.JSON(http.StatusBadRequest, gee.H{"error": err.Error()})
+			return
+		}
+		c.SetCookie("user", form.Name, 3600, "/", "", false, true)
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/me", func(c *gee.Context) {
+		user, err := c.Cookie("user")
+		if err != nil {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.SetHeader("X-Trace", c.Req.Header.Get("X-Trace"))
+		c.JSON(http.StatusOK, gee.H{"user": user, "page": c.Query("page"), "tags": []string{"a", "b"}})
+	})
+
+	client := New(t, r)
+	client.Header.Set("X-Trace", "t1")
+	client.GET("/me").Do().Status(http.StatusUnauthorized)
+	client.POST("/login").Form(url.Values{"name": {"tiam"}}).Do().Status(http.StatusNoContent)
+	if c := client.Cookie("/", "user"); c == nil || c.Value != "tiam" {
+		t.Fatalf("cookie should be saved in jar, got %v", c)
+	}
+	client.GET("/me?page=2").Do().
+		Status(http.StatusOK).
+		Header("X-Trace", "t1").
+		HeaderContains("Content-Type", gee.MIMEJSON).
+		JSON(gee.H{"tags": []string{"a", "b"}, "page": "2", "user": "tiam"}).
+		JSON(`{"user":"tiam","page":"2","tags":["a","b"]}`)
+
+	// 断言失败时报告错误
+	ft := &fakeTB{TB: t}
+	fc := New(ft, r)
+	fc.GET("/me").Query("page", "3").Cookie("user", "other").Do().
+		Status(http.StatusOK).
+		JSON(gee.H{"user": "tiam"})
+	if len(ft.failures) != 1 || !strings.Contains(ft.failures[0], `"user":"other"`) {
+		t.Fatalf("mismatched json should fail the test, got %q", ft.failures)
+	}
+
+	// 请求体编码失败时在构造请求时报告
+	ft = &fakeTB{TB: t}
+	New(ft, r).POST("/login").JSON(make(chan int)).Build()
+	if len(ft.failures) != 1 || !strings.Contains(ft.failures[0], "unsupported type") {
+		t.Fatalf("json encode error should be reported, got %q", ft.failures)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Client without New should panic")
+		}
+	}()
+	(&Client{Handler: r}).GET("/me")
+}
+
+func TestCreateTestContext(t *testing.T) {
+	auth := func(c *gee.Context) {
+		if c.Req.Header.Get("Authorization") == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gee.H{"error": "unauthorized"})
+			return
+		}
+		c.Set("user", "tiam")
+		c.Next()
+	}
+	show := func(c *gee.Context) {
+		c.JSON(http.StatusOK, gee.H{"id": c.Param("id"), "user": c.GetString("user")})
+	}
+
+	tc := CreateTestContext(t, nil)
+	tc.Run(auth, show).Status(http.StatusUnauthorized).JSON(gee.H{"error": "unauthorized"})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("Authorization", "Bearer x")
+	tc = CreateTestContext(t, req)
+	tc.Params = append(tc.Params, gee.Param{Key: "id", Value: "1"})
+	tc.Run(auth, show).Status(http.StatusOK).JSON(gee.H{"id": "1", "user": "tiam"})
+
+	// 只设置状态码时也会发送响应头
+	tc = CreateTestContext(t, nil)
+	tc.Run(func(c *gee.Context) { c.Status(http.StatusAccepted) }).Status(http.StatusAccepted).Body("")
+}
@@ -0,0 +1,150 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// hostRouter 只匹配指定 Host 的路由, pattern 例如 api.example.com 或 :tenant.example.com
+type hostRouter struct {
+	pattern string
+	labels  []string // 按 . 拆分后的 pattern, :name 匹配一级域名并作为参数
+	params  int      // pattern 中的参数个数
+	router  *router
+}
+
+func newHostRouter(pattern string) *hostRouter {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	if pattern == "" {
+		panic("gee: host pattern must not be empty")
+	}
+	h := &hostRouter{pattern: pattern, labels: strings.Split(pattern, "."), router: newRouter()}
+	for _, label := range h.labels {
+		switch {
+		case label == "" || label == ":":
+			panic(fmt.Sprintf("gee: invalid host pattern '%s'", pattern))
+		case label[0] == ':':
+			h.params++
+		case strings.ContainsAny(label, ":*"):
+			panic(fmt.Sprintf("gee: wildcard must be a whole label in host pattern '%s'", pattern))
+		}
+	}
+	return h
+}
+
+// 逐级比较域名, 参数追加到 params; 不分配内存
+func (h *hostRouter) match(host string, params *Params) bool {
+	for i, label := range h.labels {
+		part := host
+		if i < len(h.labels)-1 {
+			end := strings.IndexByte(host, '.')
+			if end < 0 {
+				return false
+			}
+			part, host = host[:end], host[end+1:]
+		}
+		if label[0] == ':' {
+			if part == "" || strings.IndexByte(part, '.') >= 0 {
+				return false
+			}
+			*params = append(*params, Param{Key: label[1:], Value: part})
+		} else if !strings.EqualFold(label, part) {
+			return false
+		}
+	}
+	return true
+}
+
+// 去掉 Host 中的端口和末尾的 .
+func requestHost(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i > strings.LastIndexByte(host, ']') {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// Host 返回只匹配指定 Host 的路由组, 全局中间件同样生效.
+// pattern 中的 :name 匹配一级域名, 例如 :tenant.example.com 通过 c.Param("tenant") 获取子域名;
+// 不带参数的 Host 优先匹配, 该 Host 下没有匹配的路由时继续查找不限 Host 的路由
+func (engine *Engine) Host(pattern string) *RouteGroup {
+	host := engine.router.addHost(pattern)
+	group := &RouteGroup{parent: engine.RouteGroup, engine: engine, host: host}
+	engine.groups = append(engine.groups, group)
+	return group
+}
+
+// 同一个 pattern 共用一个 hostRouter
+func (r *router) addHost(pattern string) *hostRouter {
+	h := newHostRouter(pattern)
+	for _, existing := range r.hosts {
+		if existing.pattern == h.pattern {
+			return existing
+		}
+	}
+	// 保持不带参数的 Host 排在前面
+	i := len(r.hosts)
+	if h.params == 0 {
+		for i = 0; i < len(r.hosts) && r.hosts[i].params == 0; i++ {
+		}
+	}
+	r.hosts = append(r.hosts, nil)
+	copy(r.hosts[i+1:], r.hosts[i:])
+	r.hosts[i] = h
+	return h
+}
+
+// 返回第一个匹配请求 Host 的 hostRouter, 参数写入 c.Params
+func (r *router) matchHost(c *Context) *hostRouter {
+	host := requestHost(c.Req.Host)
+	for _, h := range r.hosts {
+		if h.match(host, &c.Params) {
+			return h
+		}
+		c.Params = c.Params[:0]
+	}
+	return nil
+}
+
+// WrapH 将 http.Handler 包装为 HandlerFunc, 请求路径保持不变
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.W, c.Req)
+	}
+}
+
+// WrapF 将 http.HandlerFunc 包装为 HandlerFunc
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return WrapH(f)
+}
+
+// Mount 将 http.Handler 挂载到 prefix 下, 所有请求方法都会转发给 h, 路由组的中间件同样生效.
+// h 收到的请求路径去掉了 prefix, 例如挂载到 /admin 时 /admin/users 对应 /users;
+// 需要完整路径的 handler (例如 geeCache 的 HTTPPool) 使用 Any(pattern, WrapH(h)).
+// 返回注册的所有路由, 可以用于命名或添加 OpenAPI 文档
+func (group *RouteGroup) Mount(prefix string, h http.Handler) []*Route {
+	prefix = strings.TrimSuffix(prefix, "/")
+	serve := func(c *Context, path string) {
+		req := new(http.Request)
+		*req = *c.Req
+		u := *c.Req.URL
+		u.Path = path
+		u.RawPath = ""
+		req.URL = &u
+		h.ServeHTTP(c.W, req)
+	}
+	root := func(c *Context) { serve(c, "/") }
+	var routes []*Route
+	if prefix != "" {
+		routes = append(routes, group.Any(prefix, root)...)
+	}
+	routes = append(routes, group.Any(prefix+"/", root)...)
+	// 通配参数总是最后一个参数, 按位置读取, 不会与路由组或 Host 中同名的参数混淆
+	routes = append(routes, group.Any(prefix+"/*"+mountParam, func(c *Context) {
+		serve(c, "/"+c.Params[len(c.Params)-1].Value)
+	})...)
+	return routes
+}
+
+// Mount 注册的通配参数名
+const mountParam = "mountpath"
@@ -0,0 +1,132 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostRouting(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		c.SetHeader("X-Global", "1")
+		c.Next()
+	})
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, "main") })
+	r.GET("/ping", func(c *Context) { c.String(http.StatusOK, "pong") })
+
+	api := r.Host("api.example.com")
+	api.GET("/", func(c *Context) { c.String(http.StatusOK, "api") })
+	v1 := api.Group("/v1")
+	v1.GET("/users/:id", func(c *Context) { c.String(http.StatusOK, "user "+c.Param("id")) })
+
+	tenant := r.Host(":tenant.example.com")
+	tenant.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "tenant %s %d", c.Param("tenant"), len(c.Params))
+	})
+	r.Host(":region.:tenant.example.com").GET("/files/*path", func(c *Context) {
+		c.String(http.StatusOK, "%s/%s/%s", c.Param("region"), c.Param("tenant"), c.Param("path"))
+	})
+
+	cases := []struct {
+		method, host, path string
+		code               int
+		body               string
+	}{
+		{"GET", "api.example.com", "/", 200, "api"},
+		{"GET", "API.example.com:8080", "/v1/users/7", 200, "user 7"},
+		{"GET", "acme.example.com", "/", 200, "tenant acme 1"},
+		{"GET", "eu.acme.example.com", "/files/a/b.txt", 200, "eu/acme/a/b.txt"},
+		{"GET", "example.com", "/", 200, "main"},
+		// Host 下没有的路由继续查找不限 Host 的路由
+		{"GET", "api.example.com", "/ping", 200, "pong"},
+		{"GET", "www.example.org", "/v1/users/7", 404, ""},
+		{"POST", "api.example.com", "/v1/users/7", 405, ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Host = tc.host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || (tc.body != "" && w.Body.String() != tc.body) || w.Header().Get("X-Global") != "1" {
+			t.Fatalf("%s %s%s: unexpected response %d %q", tc.method, tc.host, tc.path, w.Code, w.Body.String())
+		}
+	}
+
+	var hosts []string
+	for _, info := range r.Routes() {
+		hosts = append(hosts, info.Host)
+	}
+	if fmt.Sprint(hosts) != "[  api.example.com api.example.com :tenant.example.com :region.:tenant.example.com]" {
+		t.Fatalf("unexpected route hosts %q", hosts)
+	}
+}
+
+func TestHostAllow(t *testing.T) {
+	r := New()
+	r.DELETE("/items", func(c *Context) {})
+	api := r.Host("api.example.com")
+	api.Use(func(c *Context) {
+		c.SetHeader("X-Api", "1")
+		c.Next()
+	})
+	api.GET("/items", func(c *Context) {})
+
+	// Allow 同时包含 Host 下和不限 Host 的路由的方法
+	for _, method := range []string{"POST", "OPTIONS"} {
+		req := httptest.NewRequest(method, "/items", nil)
+		req.Host = "api.example.com"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Header().Get("Allow") != "DELETE, GET, HEAD, OPTIONS" || w.Header().Get("X-Api") != "1" {
+			t.Fatalf("%s: unexpected response %d %v", method, w.Code, w.Header())
+		}
+	}
+}
+
+func TestMount(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", req.Method, req.URL.Path, req.URL.RawQuery)
+	})
+
+	r := New()
+	admin := r.Group("/admin")
+	admin.Use(func(c *Context) {
+		if c.Query("token") != "secret" {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	})
+	admin.Mount("/legacy", mux)
+	r.GET("/health", WrapF(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, req.URL.Path)
+	}))
+	r.Any("/raw/*path", WrapH(mux))
+	// 路由组中已有名为 path 的参数
+	r.Group("/files/:path").Mount("/fs", mux)
+
+	cases := []struct {
+		method, target string
+		code           int
+		body           string
+	}{
+		{"GET", "/admin/legacy/users?token=secret", 200, "GET /users token=secret"},
+		{"POST", "/admin/legacy?token=secret", 200, "POST / token=secret"},
+		{"DELETE", "/admin/legacy/?token=secret", 200, "DELETE / token=secret"},
+		{"GET", "/admin/legacy/users", 403, ""},
+		{"GET", "/health", 200, "/health"},
+		{"PUT", "/raw/a/b", 200, "PUT /raw/a/b "},
+		{"GET", "/files/docs/fs/a/b", 200, "GET /a/b "},
+		{"GET", "/files/docs/fs", 200, "GET / "},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, nil))
+		if w.Code != tc.code || w.Body.String() != tc.body {
+			t.Fatalf("%s %s: unexpected response %d %q", tc.method, tc.target, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package gee
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JWTClaimsKey 校验通过的 JWT claims 在 Context 中的 key
+const JWTClaimsKey = "gee.jwt_claims"
+
+// 支持的签名算法
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	ErrTokenMissing     = errors.New("gee: jwt token is missing")
+	ErrTokenMalformed   = errors.New("gee: jwt token is malformed")
+	ErrTokenAlgorithm   = errors.New("gee: jwt algorithm is not allowed")
+	ErrTokenUnknownKey  = errors.New("gee: jwt signing key not found")
+	ErrTokenSignature   = errors.New("gee: jwt signature is invalid")
+	ErrTokenExpired     = errors.New("gee: jwt token is expired")
+	ErrTokenNotValidYet = errors.New("gee: jwt token is not valid yet")
+	ErrTokenIssuer      = errors.New("gee: jwt issuer is invalid")
+	ErrTokenAudience    = errors.New("gee: jwt audience is invalid")
+)
+
+var defaultJWTAlgorithms = []string{HS256, RS256, EdDSA}
+
+// JWTClaims JWT 的 payload, 数字类型的 claim 为 json.Number
+type JWTClaims map[string]interface{}
+
+func (claims JWTClaims) String(key string) string {
+	s, _ := claims[key].(string)
+	return s
+}
+
+// Subject 返回 sub
+func (claims JWTClaims) Subject() string {
+	return claims.String("sub")
+}
+
+// 返回数字类型的时间 claim, 例如 exp、nbf
+func (claims JWTClaims) time(key string) (time.Time, bool, error) {
+	v, ok := claims[key]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	var seconds float64
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		if err != nil {
+			return time.Time{}, false, ErrTokenMalformed
+		}
+		seconds = f
+	case float64:
+		seconds = n
+	case int64:
+		seconds = float64(n)
+	case int:
+		seconds = float64(n)
+	default:
+		return time.Time{}, false, ErrTokenMalformed
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true, nil
+}
+
+// aud 可以是字符串或字符串数组
+func (claims JWTClaims) hasAudience(audience string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	case []string:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// JWTConfig JWT 认证配置, Key、Keys、KeyFunc 至少设置一个
+type JWTConfig struct {
+	// Key 校验签名的密钥: HS256 为 []byte, RS256 为 *rsa.PublicKey, EdDSA 为 ed25519.PublicKey
+	Key interface{}
+	// Keys 按 JWT 头部的 kid 查找密钥, 轮换密钥时新旧密钥可以同时存在
+	Keys map[string]interface{}
+	// KeyFunc 自定义密钥查找, 例如从 JWKS 获取, 优先于 Key 和 Keys
+	KeyFunc func(kid string, alg string) (interface{}, error)
+	// Algorithms 允许的算法, 默认 HS256、RS256、EdDSA; 算法还必须与密钥类型匹配
+	Algorithms []string
+	Issuer     string        // 不为空时校验 iss
+	Audience   string        // 不为空时校验 aud
+	Leeway     time.Duration // 校验 exp、nbf 时允许的时钟误差
+	// TokenLookup 获取 token 的位置, 格式为 "header:Authorization"、"query:token" 或 "cookie:jwt",
+	// 默认从 Authorization: Bearer <token> 获取
+	TokenLookup  string
+	ErrorHandler func(c *Context, err error) // 认证失败时调用, 默认返回 401
+}
+
+// JWT 校验 JWT 并将 claims 存入 Context, 通过 c.JWTClaims() 获取
+func JWT(conf JWTConfig) HandlerFunc {
+	if conf.Key == nil && conf.Keys == nil && conf.KeyFunc == nil {
+		panic("gee: JWTConfig requires Key, Keys or KeyFunc")
+	}
+	source, name := "header", "Authorization"
+	if conf.TokenLookup != "" {
+		parts := strings.SplitN(conf.TokenLookup, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			panic("gee: invalid JWTConfig.TokenLookup " + conf.TokenLookup)
+		}
+		source, name = parts[0], parts[1]
+	}
+	if conf.ErrorHandler == nil {
+		conf.ErrorHandler = func(c *Context, err error) {
+			c.SetHeader("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, H{"error": err.Error()})
+		}
+	}
+
+	return func(c *Context) {
+		var token string
+		switch source {
+		case "header":
+			token = c.Req.Header.Get(name)
+			if name == "Authorization" {
+				if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+					token = strings.TrimSpace(token[7:])
+				} else {
+					token = ""
+				}
+			}
+		case "query":
+			token = c.Query(name)
+		case "cookie":
+			if cookie, err := c.Req.Cookie(name); err == nil {
+				token = cookie.Value
+			}
+		}
+		if token == "" {
+			conf.ErrorHandler(c, ErrTokenMissing)
+			c.Abort()
+			return
+		}
+		claims, err := conf.Parse(token)
+		if err != nil {
+			conf.ErrorHandler(c, err)
+			c.Abort()
+			return
+		}
+		c.Set(JWTClaimsKey, claims)
+		c.Next()
+	}
+}
+
+// JWTClaims 返回 JWT 中间件校验通过的 claims, 没有时返回 nil
+func (c *Context) JWTClaims() JWTClaims {
+	if v, ok := c.Get(JWTClaimsKey); ok {
+		return v.(JWTClaims)
+	}
+	return nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// Parse 校验 token 的签名和 exp、nbf、iss、aud, 返回其中的 claims
+func (conf *JWTConfig) Parse(token string) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	algorithms := conf.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = defaultJWTAlgorithms
+	}
+	allowed := false
+	for _, alg := range algorithms {
+		allowed = allowed || alg == header.Alg
+	}
+	if !allowed {
+		return nil, ErrTokenAlgorithm
+	}
+	key, err := conf.lookupKey(header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if err = verifyJWT(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims JWTClaims
+	if err = decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if exp, ok, err := claims.time("exp"); err != nil {
+		return nil, err
+	} else if ok && !now.Before(exp.Add(conf.Leeway)) {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok, err := claims.time("nbf"); err != nil {
+		return nil, err
+	} else if ok && now.Add(conf.Leeway).Before(nbf) {
+		return nil, ErrTokenNotValidYet
+	}
+	if conf.Issuer != "" && claims.String("iss") != conf.Issuer {
+		return nil, ErrTokenIssuer
+	}
+	if conf.Audience != "" && !claims.hasAudience(conf.Audience) {
+		return nil, ErrTokenAudience
+	}
+	return claims, nil
+}
+
+func (conf *JWTConfig) lookupKey(kid, alg string) (interface{}, error) {
+	if conf.KeyFunc != nil {
+		return conf.KeyFunc(kid, alg)
+	}
+	if kid != "" && conf.Keys != nil {
+		if key, ok := conf.Keys[kid]; ok {
+			return key, nil
+		}
+		return nil, ErrTokenUnknownKey
+	}
+	if conf.Key != nil {
+		return conf.Key, nil
+	}
+	return nil, ErrTokenUnknownKey
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err = dec.Decode(v); err != nil {
+		return ErrTokenMalformed
+	}
+	return nil
+}
+
+// 算法必须与密钥类型一致, 防止用公钥作为 HMAC 密钥伪造签名
+func verifyJWT(alg string, key interface{}, signingInput string, signature []byte) error {
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrTokenAlgorithm
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrTokenSignature
+		}
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrTokenAlgorithm
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+			return ErrTokenSignature
+		}
+	case EdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrTokenAlgorithm
+		}
+		if !ed25519.Verify(pub, []byte(signingInput), signature) {
+			return ErrTokenSignature
+		}
+	default:
+		return ErrTokenAlgorithm
+	}
+	return nil
+}
+
+// SignJWT 签发 JWT, key 为 HS256 的 []byte、RS256 的 *rsa.PrivateKey 或 EdDSA 的 ed25519.PrivateKey,
+// kid 不为空时写入头部, 便于轮换密钥
+func SignJWT(claims JWTClaims, alg string, key interface{}, kid string) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: alg, Kid: kid, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		if alg != HS256 {
+			return "", ErrTokenAlgorithm
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		if alg != RS256 {
+			return "", ErrTokenAlgorithm
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			return "", err
+		}
+	case ed25519.PrivateKey:
+		if alg != EdDSA {
+			return "", ErrTokenAlgorithm
+		}
+		signature = ed25519.Sign(k, []byte(signingInput))
+	default:
+		return "", fmt.Errorf("gee: unsupported jwt signing key %T", key)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -1,15 +1,190 @@
 package gee
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
+// LogFormat 访问日志格式
+type LogFormat int
+
+const (
+	LogFormatText     LogFormat = iota // [GEE] 时间 | 状态码 | 耗时 | IP | 方法 路径
+	LogFormatJSON                      // 每行一个 JSON 对象
+	LogFormatCombined                  // Apache combined log format
+)
+
+// RequestIDKey 请求 ID 在 Context 中的 key
+const RequestIDKey = "gee.request_id"
+
+// LoggerConfig 访问日志配置, 零值即为默认配置
+type LoggerConfig struct {
+	Output          io.Writer                               // 日志写入的位置, 默认 os.Stderr
+	Format          LogFormat                               // 日志格式, 默认 LogFormatText
+	Formatter       func(entry *LogEntry) string            // 自定义格式, 设置后忽略 Format
+	SkipPaths       []string                                // 不记录日志的路径, 例如健康检查
+	RequestIDHeader string                                  // 请求 ID 的请求头, 默认 X-Request-ID, 缺失时自动生成
+	Fields          func(c *Context) map[string]interface{} // 追加的自定义字段
+}
+
+// LogEntry 一条访问日志
+type LogEntry struct {
+	Time      time.Time              `json:"time"`
+	Status    int                    `json:"status"`
+	Latency   time.Duration          `json:"latency"`
+	ClientIP  string                 `json:"client_ip"`
+	Method    string                 `json:"method"`
+	Path      string                 `json:"path"`
+	Query     string                 `json:"query,omitempty"`
+	Proto     string                 `json:"proto"`
+	Size      int                    `json:"size"`
+	UserAgent string                 `json:"user_agent,omitempty"`
+	Referer   string                 `json:"referer,omitempty"`
+	User      string                 `json:"user,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
 func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+// LoggerWithConfig 按配置记录访问日志, 状态码和响应大小取自 Context.W
+func LoggerWithConfig(conf LoggerConfig) HandlerFunc {
+	out := conf.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	header := conf.RequestIDHeader
+	if header == "" {
+		header = "X-Request-ID"
+	}
+	format := conf.Formatter
+	if format == nil {
+		switch conf.Format {
+		case LogFormatJSON:
+			format = formatJSON
+		case LogFormatCombined:
+			format = formatCombined
+		default:
+			format = formatText
+		}
+	}
+	skip := make(map[string]bool, len(conf.SkipPaths))
+	for _, p := range conf.SkipPaths {
+		skip[p] = true
+	}
+	var mu sync.Mutex // 保证多个请求的日志不会交错写入
+
 	return func(c *Context) {
 		// Start timer
 		t := time.Now()
+		requestID := c.Req.Header.Get(header)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Set(RequestIDKey, requestID)
+		c.SetHeader(header, requestID)
+
 		c.Next()
-		log.Printf("[%d] %s in %v", c.StatusCode, c.Req.RequestURI, time.Since(t))
+
+		if skip[c.Path] {
+			return
+		}
+		entry := &LogEntry{
+			Time:      t,
+			Status:    c.W.Status(),
+			Latency:   time.Since(t),
+			ClientIP:  c.ClientIP(),
+			Method:    c.Method,
+			Path:      c.Path,
+			Query:     c.Req.URL.RawQuery,
+			Proto:     c.Req.Proto,
+			Size:      c.W.Size(),
+			UserAgent: c.Req.UserAgent(),
+			Referer:   c.Req.Referer(),
+			RequestID: requestID,
+		}
+		if entry.Size < 0 {
+			entry.Size = 0
+		}
+		if user, _, ok := c.Req.BasicAuth(); ok {
+			entry.User = user
+		}
+		if conf.Fields != nil {
+			entry.Fields = conf.Fields(c)
+		}
+		line := format(entry)
+		mu.Lock()
+		_, _ = io.WriteString(out, line)
+		mu.Unlock()
+	}
+}
+
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// 按 key 排序输出自定义字段, 保证日志稳定
+func formatFields(fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	return b.String()
+}
+
+func formatText(e *LogEntry) string {
+	path := e.Path
+	if e.Query != "" {
+		path += "?" + e.Query
+	}
+	return fmt.Sprintf("[GEE] %s | %3d | %13v | %15s | %-7s %q | %s | %q%s\n",
+		e.Time.Format("2006/01/02 - 15:04:05"), e.Status, e.Latency, e.ClientIP,
+		e.Method, path, e.RequestID, e.UserAgent, formatFields(e.Fields))
+}
+
+func formatJSON(e *LogEntry) string {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf("{\"error\":%q}\n", err.Error())
+	}
+	return string(b) + "\n"
+}
+
+// host ident authuser [date] "request" status bytes "referer" "user-agent"
+// 路径重新转义后写入, 避免 %0A 之类的字符伪造日志行
+func formatCombined(e *LogEntry) string {
+	uri := (&url.URL{Path: e.Path, RawQuery: e.Query}).RequestURI()
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %d %q %q%s\n",
+		e.ClientIP, orDash(e.User), e.Time.Format("02/Jan/2006:15:04:05 -0700"), e.Method, uri, e.Proto,
+		e.Status, e.Size, orDash(e.Referer), orDash(e.UserAgent), formatFields(e.Fields))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
 	}
+	return s
 }
@@ -0,0 +1,92 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(LoggerWithConfig(LoggerConfig{
+		Output:    &buf,
+		Format:    LogFormatJSON,
+		SkipPaths: []string{"/healthz"},
+		Fields: func(c *Context) map[string]interface{} {
+			return map[string]interface{}{"user": c.GetString("user")}
+		},
+	}))
+	r.GET("/healthz", func(c *Context) { c.String(http.StatusOK, "ok") })
+	r.GET("/hello", func(c *Context) {
+		c.Set("user", "tiam")
+		// 没有调用 Status, 状态码也应被记录为 200
+		c.W.Write([]byte("hello"))
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("skipped path shouldn't be logged: %s", buf.String())
+	}
+
+	req := httptest.NewRequest("GET", "/hello?a=1", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var entry LogEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Status != http.StatusOK || entry.Size != 5 || entry.Path != "/hello" || entry.Query != "a=1" ||
+		entry.RequestID != "req-1" || entry.Fields["user"] != "tiam" {
+		t.Fatalf("unexpected log entry %+v", entry)
+	}
+	if w.Header().Get("X-Request-ID") != "req-1" {
+		t.Fatal("request id should be echoed in response header")
+	}
+}
+
+func TestLoggerCombined(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(LoggerWithConfig(LoggerConfig{Output: &buf, Format: LogFormatCombined}))
+	r.GET("/missing", func(c *Context) { c.Status(http.StatusNotFound) })
+
+	req := httptest.NewRequest("GET", "/missing", nil)
+	req.SetBasicAuth("tiam", "secret")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	line := buf.String()
+	if !strings.HasPrefix(line, "192.0.2.1 - tiam [") || !strings.Contains(line, `"GET /missing HTTP/1.1" 404 0 "-" "-"`) {
+		t.Fatalf("unexpected combined log %q", line)
+	}
+
+	buf.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing%0A1.2.3.4%20-%20-?a=%0A", nil))
+	if line = buf.String(); strings.Count(line, "\n") != 1 || !strings.Contains(line, `"GET /missing%0A1.2.3.4%20-%20-?a=%0A HTTP/1.1"`) {
+		t.Fatalf("path should be escaped in combined log %q", line)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := New()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.2")
+
+	req.RemoteAddr = "10.0.0.1:1234"
+	c := NewContext(httptest.NewRecorder(), req)
+	c.engine = r
+	if ip := c.ClientIP(); ip != "1.2.3.4" {
+		t.Fatalf("expect 1.2.3.4 from trusted proxy, got %s", ip)
+	}
+
+	req.RemoteAddr = "8.8.8.8:1234"
+	if ip := c.ClientIP(); ip != "8.8.8.8" {
+		t.Fatalf("X-Forwarded-For from untrusted peer should be ignored, got %s", ip)
+	}
+}
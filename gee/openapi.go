@@ -0,0 +1,672 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OpenAPI 3.0 文档, 由已注册的路由和 Request / Response 标注的结构体类型生成
+
+type OpenAPISpec struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Servers    []OpenAPIServer                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components *OpenAPIComponents                      `json:"components,omitempty"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"` // path、query 或 header
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required,omitempty"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPISchema JSON Schema 的一个子集, 足以描述 binding 支持的类型和校验规则
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Default              string                    `json:"default,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// 路由上的文档标注
+type routeDoc struct {
+	summary     string
+	description string
+	tags        []string
+	deprecated  bool
+	hidden      bool
+	request     interface{}
+	responses   map[int]interface{}
+	codes       []int // 保持标注顺序
+}
+
+// Summary 设置接口摘要
+func (r *Route) Summary(summary string) *Route {
+	r.doc.summary = summary
+	return r
+}
+
+// Description 设置接口的详细说明
+func (r *Route) Description(description string) *Route {
+	r.doc.description = description
+	return r
+}
+
+// Tags 设置接口分组
+func (r *Route) Tags(tags ...string) *Route {
+	r.doc.tags = append(r.doc.tags, tags...)
+	return r
+}
+
+// Deprecated 将接口标记为已废弃
+func (r *Route) Deprecated() *Route {
+	r.doc.deprecated = true
+	return r
+}
+
+// Hidden 不在 OpenAPI 文档中出现
+func (r *Route) Hidden() *Route {
+	r.doc.hidden = true
+	return r
+}
+
+// Request 标注请求的结构体类型, 例如 Request(CreateUser{}).
+// 带 uri tag 的字段为路径参数, header tag 为请求头参数;
+// GET、DELETE 等没有请求体的方法中 form tag 的字段为 query 参数, 其余方法中其他字段组成 JSON 请求体
+func (r *Route) Request(obj interface{}) *Route {
+	r.doc.request = obj
+	return r
+}
+
+// Response 标注状态码对应的响应类型, obj 为 nil 时表示没有响应体
+func (r *Route) Response(code int, obj interface{}) *Route {
+	if r.doc.responses == nil {
+		r.doc.responses = make(map[int]interface{})
+	}
+	if _, ok := r.doc.responses[code]; !ok {
+		r.doc.codes = append(r.doc.codes, code)
+	}
+	r.doc.responses[code] = obj
+	return r
+}
+
+// OpenAPIConfig 文档配置
+type OpenAPIConfig struct {
+	Title       string   // 默认 "API"
+	Version     string   // 默认 "1.0.0"
+	Description string   // 文档说明
+	Servers     []string // 服务地址, 例如 https://api.example.com
+	// Path 文档路径, 默认 "/openapi", 分别提供 Path+".json" 和 Path+".yaml"
+	Path string
+	// DocsPath 文档页面路径, 默认 "/docs", 为 "-" 时不提供
+	DocsPath string
+}
+
+func (conf *OpenAPIConfig) setDefaults() {
+	if conf.Title == "" {
+		conf.Title = "API"
+	}
+	if conf.Version == "" {
+		conf.Version = "1.0.0"
+	}
+	if conf.Path == "" {
+		conf.Path = "/openapi"
+	}
+	if conf.DocsPath == "" {
+		conf.DocsPath = "/docs"
+	}
+}
+
+// OpenAPI 根据当前已注册的路由生成文档
+func (engine *Engine) OpenAPI(conf OpenAPIConfig) *OpenAPISpec {
+	conf.setDefaults()
+	spec := &OpenAPISpec{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: conf.Title, Description: conf.Description, Version: conf.Version},
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+	}
+	for _, s := range conf.Servers {
+		spec.Servers = append(spec.Servers, OpenAPIServer{URL: s})
+	}
+	gen := &schemaGenerator{schemas: make(map[string]*OpenAPISchema), names: make(map[reflect.Type]string)}
+	for _, r := range engine.router.routes {
+		method := strings.ToLower(r.Method)
+		if r.doc.hidden || !openAPIMethods[method] {
+			continue
+		}
+		path := openAPIPath(r.Pattern)
+		if spec.Paths[path] == nil {
+			spec.Paths[path] = make(map[string]*OpenAPIOperation)
+		}
+		spec.Paths[path][method] = gen.operation(r)
+	}
+	if len(gen.schemas) > 0 {
+		spec.Components = &OpenAPIComponents{Schemas: gen.schemas}
+	}
+	return spec
+}
+
+// ServeOpenAPI 注册文档路由, 文档在第一次请求时生成, 因此应在注册完其他路由后再开始处理请求
+func (group *RouteGroup) ServeOpenAPI(conf OpenAPIConfig) {
+	conf.setDefaults()
+	var (
+		once     sync.Once
+		jsonData []byte
+		yamlData []byte
+		err      error
+	)
+	build := func() {
+		spec := group.engine.OpenAPI(conf)
+		if jsonData, err = json.MarshalIndent(spec, "", "  "); err != nil {
+			return
+		}
+		yamlData, err = marshalYAML(spec)
+	}
+	serve := func(contentType string, data *[]byte) HandlerFunc {
+		return func(c *Context) {
+			once.Do(build)
+			c.render(http.StatusOK, contentType, *data, err)
+		}
+	}
+	group.GET(conf.Path+".json", serve(MIMEJSON+"; charset=utf-8", &jsonData)).Hidden()
+	group.GET(conf.Path+".yaml", serve(MIMEYAML+"; charset=utf-8", &yamlData)).Hidden()
+	if conf.DocsPath == "-" {
+		return
+	}
+	specURL := group.prefix + conf.Path + ".json"
+	group.GET(conf.DocsPath, func(c *Context) {
+		var buf bytes.Buffer
+		err := docsTemplate.Execute(&buf, H{"title": conf.Title, "spec": specURL})
+		c.render(http.StatusOK, MIMEHTML+"; charset=utf-8", buf.Bytes(), err)
+	}).Hidden()
+}
+
+var openAPIMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// /users/:id<int>/*path 转换为 /users/{id}/{path}
+func openAPIPath(pattern string) string {
+	parts := strings.Split(pattern, "/")
+	for i, p := range parts {
+		if spec, ok, _ := parseSegment(p); ok {
+			parts[i] = "{" + spec.key + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// 路由中的参数, 按出现顺序
+func patternParams(pattern string) []paramSpec {
+	var params []paramSpec
+	for _, p := range strings.Split(pattern, "/") {
+		if spec, ok, _ := parseSegment(p); ok && spec.key != "" {
+			params = append(params, spec)
+		}
+	}
+	return params
+}
+
+// 没有标注类型的路径参数按约束推断 schema
+func paramSchema(spec paramSpec) *OpenAPISchema {
+	switch spec.typ {
+	case "int":
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case "uint":
+		min := 0.0
+		return &OpenAPISchema{Type: "integer", Format: "int64", Minimum: &min}
+	case "uuid":
+		return &OpenAPISchema{Type: "string", Format: "uuid"}
+	case "alpha":
+		return &OpenAPISchema{Type: "string", Pattern: "^[A-Za-z]+$"}
+	}
+	if spec.regex != "" {
+		return &OpenAPISchema{Type: "string", Pattern: "^(?:" + spec.regex + ")$"}
+	}
+	return &OpenAPISchema{Type: "string"}
+}
+
+// 为每个结构体类型生成 components/schemas 中的定义, 同一类型只生成一次
+type schemaGenerator struct {
+	schemas map[string]*OpenAPISchema
+	names   map[reflect.Type]string
+}
+
+func (g *schemaGenerator) operation(r *Route) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		OperationID: r.name,
+		Summary:     r.doc.summary,
+		Description: r.doc.description,
+		Tags:        r.doc.tags,
+		Deprecated:  r.doc.deprecated,
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+	params := make(map[string]*OpenAPIParameter)
+	if r.doc.request != nil {
+		hasBody := r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch
+		body := &OpenAPISchema{Type: "object"}
+		plain := true // 所有字段都属于请求体时直接引用结构体的定义
+		for _, f := range structFields(reflect.TypeOf(r.doc.request)) {
+			var in, name string
+			if name = tagName(f.field, "uri"); name != "" {
+				in = "path"
+			} else if name = tagName(f.field, "header"); name != "" {
+				in = "header"
+			} else if name = tagName(f.field, "form"); name != "" && !hasBody {
+				in = "query"
+			}
+			if in == "" {
+				if hasBody {
+					g.addProperty(body, f)
+				}
+				continue
+			}
+			plain = false
+			schema := g.fieldSchema(f.field)
+			p := &OpenAPIParameter{Name: name, In: in, Required: in == "path" || f.required, Schema: schema}
+			p.Description, schema.Description = schema.Description, ""
+			params[in+":"+name] = p
+			op.Parameters = append(op.Parameters, p)
+		}
+		if hasBody && len(body.Properties) > 0 {
+			if plain {
+				body = g.schema(derefType(reflect.TypeOf(r.doc.request)))
+			}
+			op.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content:  map[string]*OpenAPIMediaType{MIMEJSON: {Schema: body}},
+			}
+		}
+	}
+	// 没有标注的路径参数按约束推断类型, 没有约束时为字符串
+	for _, spec := range patternParams(r.Pattern) {
+		if params["path:"+spec.key] == nil {
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name: spec.key, In: "path", Required: true, Schema: paramSchema(spec),
+			})
+		}
+	}
+
+	if len(r.doc.codes) == 0 {
+		op.Responses["200"] = &OpenAPIResponse{Description: http.StatusText(http.StatusOK)}
+	}
+	for _, code := range r.doc.codes {
+		resp := &OpenAPIResponse{Description: http.StatusText(code)}
+		if obj := r.doc.responses[code]; obj != nil {
+			resp.Content = map[string]*OpenAPIMediaType{MIMEJSON: {Schema: g.schema(reflect.TypeOf(obj))}}
+		}
+		op.Responses[strconv.Itoa(code)] = resp
+	}
+	return op
+}
+
+type schemaField struct {
+	field    reflect.StructField
+	name     string // JSON 中的字段名, 为空时不出现在 JSON 中
+	required bool
+}
+
+// 导出字段按 json tag 命名, 没有 tag 的内嵌结构体展开
+func structFields(t reflect.Type) []schemaField {
+	t = derefType(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []schemaField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		name := tag
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			name = tag[:j]
+		}
+		if sf.Anonymous && name == "" && isStruct(sf.Type) {
+			fields = append(fields, structFields(sf.Type)...)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		// json:"-" 的字段不属于请求体, 但仍可以是路径、query 或请求头参数
+		if tag == "-" {
+			name = ""
+		} else if name == "" {
+			name = sf.Name
+		}
+		_, required := bindingRules(sf)["required"]
+		fields = append(fields, schemaField{field: sf, name: name, required: required})
+	}
+	return fields
+}
+
+func (g *schemaGenerator) addProperty(s *OpenAPISchema, f schemaField) {
+	if f.name == "" {
+		return
+	}
+	if s.Properties == nil {
+		s.Properties = make(map[string]*OpenAPISchema)
+	}
+	s.Properties[f.name] = g.fieldSchema(f.field)
+	if f.required {
+		s.Required = append(s.Required, f.name)
+	}
+}
+
+// 字段的 schema, 附加 binding 规则和 description、form tag 中的默认值
+func (g *schemaGenerator) fieldSchema(sf reflect.StructField) *OpenAPISchema {
+	schema := g.schema(sf.Type)
+	if schema.Ref != "" {
+		// $ref 不能与其他属性并列, 不添加约束
+		return schema
+	}
+	schema.Description = sf.Tag.Get("description")
+	if sf.Type == timeType {
+		if layout := sf.Tag.Get("time_format"); layout != "" {
+			schema.Format = ""
+			schema.Description = strings.TrimSpace(schema.Description + " (" + layout + ")")
+		}
+	}
+	for _, tag := range []string{"form", "uri", "header"} {
+		if def, ok := tagOption(sf.Tag.Get(tag), "default"); ok {
+			schema.Default = def
+		}
+	}
+	rules := bindingRules(sf)
+	if schema.Type == "array" {
+		if v, ok := rules["min"]; ok {
+			schema.MinItems = intParam(v)
+		}
+		if v, ok := rules["max"]; ok {
+			schema.MaxItems = intParam(v)
+		}
+		return schema
+	}
+	if v, ok := rules["oneof"]; ok {
+		schema.Enum = strings.Fields(v)
+	}
+	if _, ok := rules["email"]; ok {
+		schema.Format = "email"
+	}
+	switch schema.Type {
+	case "string":
+		if v, ok := rules["min"]; ok {
+			schema.MinLength = intParam(v)
+		}
+		if v, ok := rules["max"]; ok {
+			schema.MaxLength = intParam(v)
+		}
+		if v, ok := rules["len"]; ok {
+			schema.MinLength, schema.MaxLength = intParam(v), intParam(v)
+		}
+	case "integer", "number":
+		if v, ok := rules["min"]; ok {
+			schema.Minimum = floatParam(v)
+		}
+		if v, ok := rules["max"]; ok {
+			schema.Maximum = floatParam(v)
+		}
+	}
+	return schema
+}
+
+// 解析 binding tag, 例如 "required,min=3" 返回 {"required": "", "min": "3"}
+func bindingRules(sf reflect.StructField) map[string]string {
+	key := "binding"
+	if v, ok := Validator.(*DefaultValidator); ok {
+		key = v.TagName
+	}
+	rules := make(map[string]string)
+	tag := sf.Tag.Get(key)
+	if tag == "" || tag == "-" {
+		return rules
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		name, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		rules[name] = param
+	}
+	return rules
+}
+
+func intParam(s string) *int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func floatParam(s string) *float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+// binding 使用的 tag 名, 不含选项
+func tagName(sf reflect.StructField, tag string) string {
+	name := sf.Tag.Get(tag)
+	if i := strings.IndexByte(name, ','); i >= 0 {
+		name = name[:i]
+	}
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+var fileHeaderType = reflect.TypeOf(multipart.FileHeader{})
+
+func (g *schemaGenerator) schema(t reflect.Type) *OpenAPISchema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t, nullable = t.Elem(), true
+	}
+	switch t {
+	case timeType:
+		return &OpenAPISchema{Type: "string", Format: "date-time", Nullable: nullable}
+	case durationType:
+		return &OpenAPISchema{Type: "string", Format: "duration", Nullable: nullable}
+	case fileHeaderType:
+		return &OpenAPISchema{Type: "string", Format: "binary"}
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		// 自定义 JSON 编码的类型无法推断结构
+		return &OpenAPISchema{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean", Nullable: nullable}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &OpenAPISchema{Type: "integer", Format: "int32", Nullable: nullable}
+	case reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64", Nullable: nullable}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float", Nullable: nullable}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double", Nullable: nullable}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string", Nullable: nullable}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json 将 []byte 编码为 base64 字符串
+			return &OpenAPISchema{Type: "string", Format: "byte", Nullable: nullable}
+		}
+		return &OpenAPISchema{Type: "array", Items: g.schema(t.Elem()), Nullable: nullable}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: g.schema(t.Elem()), Nullable: nullable}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + g.define(t)}
+	}
+	// interface{} 等任意类型
+	return &OpenAPISchema{}
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *OpenAPISchema {
+	s := &OpenAPISchema{Type: "object"}
+	for _, f := range structFields(t) {
+		g.addProperty(s, f)
+	}
+	return s
+}
+
+var schemaNameReplacer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// 在 components 中定义结构体, 返回定义名; 先登记名称再展开字段, 以支持递归类型
+func (g *schemaGenerator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := schemaNameReplacer.ReplaceAllString(t.Name(), "_")
+	if _, used := g.schemas[name]; used {
+		// 不同包中的同名类型加上包名区分
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndexByte(pkg, '/')+1:]
+		name = schemaNameReplacer.ReplaceAllString(pkg, "_") + "." + name
+		for i, base := 2, name; g.schemas[name] != nil; i++ {
+			name = base + strconv.Itoa(i)
+		}
+	}
+	g.names[t] = name
+	g.schemas[name] = &OpenAPISchema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return name
+}
+
+// 文档页面, 不依赖外部资源, 直接读取 JSON 文档渲染接口列表
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.title}}</title>
+<style>
+body{font-family:-apple-system,Helvetica,Arial,sans-serif;margin:0 auto;max-width:960px;padding:24px;color:#222}
+details{border:1px solid #ddd;border-radius:4px;margin:8px 0}
+summary{cursor:pointer;padding:8px;font-family:monospace}
+.method{display:inline-block;width:64px;font-weight:bold;text-transform:uppercase}
+.get{color:#2b7}.post{color:#27c}.put{color:#c80}.patch{color:#a5c}.delete{color:#c33}
+.deprecated{text-decoration:line-through}
+pre{background:#f6f6f6;margin:0;padding:8px;overflow:auto}
+h2{border-bottom:1px solid #eee}
+</style>
+</head>
+<body>
+<h1>{{.title}}</h1>
+<p><a href="{{.spec}}">{{.spec}}</a></p>
+<div id="api"></div>
+<script>
+fetch({{.spec}}).then(function(r){return r.json()}).then(function(spec){
+  var root=document.getElementById("api"),groups={};
+  if(spec.info.description){var p=document.createElement("p");p.textContent=spec.info.description;root.appendChild(p)}
+  Object.keys(spec.paths).forEach(function(path){
+    Object.keys(spec.paths[path]).forEach(function(method){
+      var op=spec.paths[path][method];
+      (op.tags||["default"]).forEach(function(tag){(groups[tag]=groups[tag]||[]).push([method,path,op])});
+    });
+  });
+  Object.keys(groups).sort().forEach(function(tag){
+    var h=document.createElement("h2");h.textContent=tag;root.appendChild(h);
+    groups[tag].forEach(function(item){
+      var d=document.createElement("details"),s=document.createElement("summary"),m=document.createElement("span");
+      m.className="method "+item[0];m.textContent=item[0];s.appendChild(m);
+      s.appendChild(document.createTextNode(item[1]+(item[2].summary?"  "+item[2].summary:"")));
+      if(item[2].deprecated)s.className="deprecated";
+      var pre=document.createElement("pre"),op=Object.assign({},item[2]);
+      delete op.summary;delete op.tags;
+      pre.textContent=JSON.stringify(op,null,2);
+      d.appendChild(s);d.appendChild(pre);root.appendChild(d);
+    });
+  });
+  if(spec.components&&spec.components.schemas){
+    var h=document.createElement("h2");h.textContent="schemas";root.appendChild(h);
+    Object.keys(spec.components.schemas).sort().forEach(function(name){
+      var d=document.createElement("details"),s=document.createElement("summary"),pre=document.createElement("pre");
+      s.textContent=name;pre.textContent=JSON.stringify(spec.components.schemas[name],null,2);
+      d.appendChild(s);d.appendChild(pre);root.appendChild(d);
+    });
+  }
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,114 @@
+package gee
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type apiUser struct {
+	ID      int64      `json:"id"`
+	Name    string     `json:"name" binding:"required,min=2,max=32" description:"display name"`
+	Email   string     `json:"email,omitempty" binding:"email"`
+	Role    string     `json:"role" binding:"oneof=admin member"`
+	Friends []*apiUser `json:"friends,omitempty"`
+	Created time.Time  `json:"created"`
+	secret  string
+}
+
+type apiListUsers struct {
+	Page  int    `form:"page,default=1" binding:"min=1"`
+	Token string `header:"X-Token" binding:"required"`
+}
+
+type apiUpdateUser struct {
+	ID   int64  `uri:"id" json:"-"`
+	Name string `json:"name" binding:"required"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	r := New()
+	r.GET("/users", showUser).Request(apiListUsers{}).Response(200, []apiUser{}).Tags("users")
+	r.POST("/users", showUser).Request(apiUser{}).Response(201, apiUser{}).Response(400, ValidationErrors{})
+	r.PUT("/users/:id", showUser).Name("user.update").Request(&apiUpdateUser{}).Response(204, nil)
+	r.GET("/files/*filepath", showUser).Summary("download").Deprecated()
+	r.GET("/internal", showUser).Hidden()
+
+	spec := r.OpenAPI(OpenAPIConfig{Title: "demo"})
+	if spec.Info.Title != "demo" || spec.Info.Version != "1.0.0" || len(spec.Paths) != 3 {
+		t.Fatalf("unexpected spec %+v", spec)
+	}
+
+	list := spec.Paths["/users"]["get"]
+	if len(list.Parameters) != 2 || list.RequestBody != nil || list.Tags[0] != "users" {
+		t.Fatalf("unexpected list operation %+v", list)
+	}
+	page, token := list.Parameters[0], list.Parameters[1]
+	if page.In != "query" || page.Name != "page" || page.Required || page.Schema.Default != "1" || *page.Schema.Minimum != 1 {
+		t.Fatalf("unexpected page parameter %+v %+v", page, page.Schema)
+	}
+	if token.In != "header" || token.Name != "X-Token" || !token.Required {
+		t.Fatalf("unexpected token parameter %+v", token)
+	}
+	if items := list.Responses["200"].Content[MIMEJSON].Schema; items.Type != "array" || items.Items.Ref != "#/components/schemas/apiUser" {
+		t.Fatalf("unexpected list response %+v", items)
+	}
+
+	create := spec.Paths["/users"]["post"]
+	if create.RequestBody.Content[MIMEJSON].Schema.Ref != "#/components/schemas/apiUser" ||
+		create.Responses["201"] == nil || create.Responses["400"].Content[MIMEJSON].Schema.Type != "array" {
+		t.Fatalf("unexpected create operation %+v", create)
+	}
+
+	update := spec.Paths["/users/{id}"]["put"]
+	if update.OperationID != "user.update" || len(update.Parameters) != 1 || update.Parameters[0].Schema.Type != "integer" {
+		t.Fatalf("unexpected update operation %+v", update)
+	}
+	body := update.RequestBody.Content[MIMEJSON].Schema
+	if body.Ref != "" || len(body.Properties) != 1 || body.Required[0] != "name" || update.Responses["204"].Content != nil {
+		t.Fatalf("unexpected update body %+v", body)
+	}
+
+	files := spec.Paths["/files/{filepath}"]["get"]
+	if !files.Deprecated || files.Parameters[0].Name != "filepath" || files.Responses["200"] == nil {
+		t.Fatalf("unexpected files operation %+v", files)
+	}
+
+	user := spec.Components.Schemas["apiUser"]
+	name := user.Properties["name"]
+	if len(user.Properties) != 6 || len(user.Required) != 1 || name.Description != "display name" || *name.MinLength != 2 || *name.MaxLength != 32 {
+		t.Fatalf("unexpected user schema %+v", user)
+	}
+	if user.Properties["email"].Format != "email" || len(user.Properties["role"].Enum) != 2 ||
+		user.Properties["friends"].Items.Ref != "#/components/schemas/apiUser" || user.Properties["created"].Format != "date-time" {
+		t.Fatalf("unexpected user properties %+v", user.Properties)
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	r := New()
+	api := r.Group("/api")
+	api.ServeOpenAPI(OpenAPIConfig{Title: "demo"})
+	api.GET("/users/:id", showUser).Response(200, apiUser{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	var spec OpenAPISpec
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil || spec.OpenAPI != "3.0.3" || len(spec.Paths) != 1 {
+		t.Fatalf("unexpected json spec %v %s", err, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.yaml", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), MIMEYAML) || !strings.Contains(w.Body.String(), "\n  \"/api/users/{id}\":\n    get:\n") {
+		t.Fatalf("unexpected yaml spec %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/docs", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), MIMEHTML) || !strings.Contains(w.Body.String(), `fetch("/api/openapi.json")`) {
+		t.Fatalf("unexpected docs page %s", w.Body.String())
+	}
+}
@@ -0,0 +1,14 @@
+module qitian/gee/ormstore
+
+go 1.18
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.16
+	qitian/gee v0.0.0
+	qitian/geeOrm v0.0.0
+)
+
+replace (
+	qitian/gee => ../
+	qitian/geeOrm => ../../geeOrm
+)
@@ -0,0 +1,77 @@
+// Package ormstore 基于 geeOrm 的 gee 会话存储, 会话保存在 GeeSession 表中.
+// 单独作为一个 module, 只有用到它的项目才依赖 geeOrm 和数据库驱动:
+//
+//	import _ "github.com/mattn/go-sqlite3"
+//
+//	engine, _ := geeOrm.NewEngine("sqlite3", "gee.db")
+//	store, _ := ormstore.New(engine)
+//	r.Use(gee.Sessions(gee.SessionConfig{Store: store}))
+package ormstore
+
+import (
+	"qitian/gee"
+	"qitian/geeOrm"
+	"qitian/geeOrm/session"
+	"time"
+)
+
+// GeeSession 会话表的结构
+type GeeSession struct {
+	ID     string `geeorm:"PRIMARY KEY"`
+	Data   []byte
+	Expiry int64 // 过期时间, unix 秒
+}
+
+// Store 实现 gee.SessionStore
+type Store struct {
+	engine *geeOrm.Engine
+}
+
+var _ gee.SessionStore = (*Store)(nil)
+
+// New 使用 engine 创建会话存储, 会话表不存在时自动创建
+func New(engine *geeOrm.Engine) (*Store, error) {
+	s := engine.NewSession().Model(&GeeSession{})
+	if !s.HasTable() {
+		if err := s.CreateTable(); err != nil {
+			return nil, err
+		}
+	}
+	return &Store{engine: engine}, nil
+}
+
+func (st *Store) Get(id string) ([]byte, error) {
+	var rows []GeeSession
+	if err := st.engine.NewSession().Where("ID = ?", id).Limit(1).Find(&rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	if rows[0].Expiry <= time.Now().Unix() {
+		return nil, st.Delete(id)
+	}
+	return rows[0].Data, nil
+}
+
+// Set 先删除再插入, 在同一个事务中完成
+func (st *Store) Set(id string, data []byte, ttl time.Duration) error {
+	record := &GeeSession{ID: id, Data: data, Expiry: time.Now().Add(ttl).Unix()}
+	_, err := st.engine.Transaction(func(s *session.Session) (interface{}, error) {
+		if _, err := s.Model(record).Where("ID = ?", id).Delete(); err != nil {
+			return nil, err
+		}
+		return s.Insert(record)
+	})
+	return err
+}
+
+func (st *Store) Delete(id string) error {
+	_, err := st.engine.NewSession().Model(&GeeSession{}).Where("ID = ?", id).Delete()
+	return err
+}
+
+// Cleanup 删除所有过期的会话, 可以定期调用
+func (st *Store) Cleanup() (int64, error) {
+	return st.engine.NewSession().Model(&GeeSession{}).Where("Expiry <= ?", time.Now().Unix()).Delete()
+}
@@ -0,0 +1,70 @@
+package ormstore
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"qitian/geeOrm"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	engine, err := geeOrm.NewEngine("sqlite3", filepath.Join(t.TempDir(), "gee.db"))
+	if err != nil {
+		t.Fatal("failed to connect", err)
+	}
+	t.Cleanup(engine.Close)
+	store, err := New(engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestStore(t *testing.T) {
+	store := newTestStore(t)
+
+	if data, err := store.Get("missing"); err != nil || data != nil {
+		t.Fatalf("missing session should be nil, got %q %v", data, err)
+	}
+	if err := store.Set("s1", []byte("v1"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// 再次保存覆盖原有数据
+	if err := store.Set("s1", []byte("v2"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := store.Get("s1"); err != nil || !bytes.Equal(data, []byte("v2")) {
+		t.Fatalf("unexpected session data %q %v", data, err)
+	}
+
+	if err := store.Delete("s1"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := store.Get("s1"); err != nil || data != nil {
+		t.Fatalf("deleted session should be nil, got %q %v", data, err)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.Set("expired", []byte("old"), -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := store.Get("expired"); err != nil || data != nil {
+		t.Fatalf("expired session should be nil, got %q %v", data, err)
+	}
+
+	_ = store.Set("a", []byte("a"), -time.Second)
+	_ = store.Set("b", []byte("b"), -time.Second)
+	_ = store.Set("c", []byte("c"), time.Hour)
+	if n, err := store.Cleanup(); err != nil || n != 2 {
+		t.Fatalf("expect 2 sessions cleaned up, got %d %v", n, err)
+	}
+	if data, _ := store.Get("c"); !bytes.Equal(data, []byte("c")) {
+		t.Fatalf("live session should be kept, got %q", data)
+	}
+}
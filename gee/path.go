@@ -0,0 +1,146 @@
+package gee
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// cleanPath 规范化 URL 路径: 去掉重复的 /, 处理 . 和 .., 保留末尾的 /
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	cleaned := path.Clean(p)
+	if p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// 未匹配到路由时尝试修正路径, 返回重定向的目标 URL:
+// RedirectTrailingSlash 增删末尾的 /, RedirectFixedPath 清理路径后忽略大小写查找
+func (r *router) fixPath(c *Context, host *hostRouter, p string) (string, bool) {
+	engine := c.engine
+	if engine == nil || c.Method == http.MethodConnect {
+		return "", false
+	}
+	defer func() { c.Params = c.Params[:0] }()
+
+	if engine.RedirectTrailingSlash && p != "/" {
+		fixed := p + "/"
+		if strings.HasSuffix(p, "/") {
+			fixed = p[:len(p)-1]
+		}
+		if r.exists(c, host, fixed) {
+			return redirectLocation(c, fixed, p), true
+		}
+	}
+	if engine.RedirectFixedPath {
+		cleaned := cleanPath(p)
+		candidates := []string{cleaned}
+		if engine.RedirectTrailingSlash && cleaned != "/" {
+			if strings.HasSuffix(cleaned, "/") {
+				candidates = append(candidates, cleaned[:len(cleaned)-1])
+			} else {
+				candidates = append(candidates, cleaned+"/")
+			}
+		}
+		for _, candidate := range candidates {
+			if fixed, ok := r.findCaseInsensitive(c.Method, host, candidate); ok && fixed != p {
+				return redirectLocation(c, fixed, p), true
+			}
+		}
+	}
+	return "", false
+}
+
+// 判断 p 在 Host 路由或不限 Host 的路由中是否存在, 与正常分发一样 HEAD 可以匹配 GET 路由
+func (r *router) exists(c *Context, host *hostRouter, p string) bool {
+	c.Params = c.Params[:0]
+	if host != nil && host.router.lookup(c.Method, p, &c.Params) != nil {
+		return true
+	}
+	c.Params = c.Params[:0]
+	return r.lookup(c.Method, p, &c.Params) != nil
+}
+
+func (r *router) findCaseInsensitive(method string, host *hostRouter, p string) (string, bool) {
+	routers := []*router{r}
+	if host != nil {
+		routers = []*router{host.router, r}
+	}
+	methods := []string{method}
+	if method == http.MethodHead {
+		methods = append(methods, http.MethodGet)
+	}
+	for _, rt := range routers {
+		for _, m := range methods {
+			if root := rt.roots[m]; root != nil {
+				if fixed := root.findCaseInsensitive(p, make([]byte, 0, len(p))); fixed != nil {
+					return string(fixed), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// 保留查询参数; 按转义路径匹配时 fixed 也是转义后的形式
+func redirectLocation(c *Context, fixed string, matched string) string {
+	u := *c.Req.URL
+	if matched == u.RawPath && u.RawPath != "" {
+		u.RawPath = fixed
+		if unescaped, err := url.PathUnescape(fixed); err == nil {
+			u.Path = unescaped
+		}
+	} else {
+		u.Path, u.RawPath = fixed, ""
+	}
+	// 只保留路径和查询参数, 避免 //evil.com 之类的路径被当作其他站点
+	location := (&url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery}).String()
+	if strings.HasPrefix(location, "//") {
+		location = "/" + strings.TrimLeft(location, "/")
+	}
+	return location
+}
+
+// 忽略大小写查找 path, 返回路由中静态部分使用注册时的大小写的路径, 参数部分保持原样;
+// 与 search 一样按 static > param > catchAll 的顺序回溯
+func (n *node) findCaseInsensitive(p string, buf []byte) []byte {
+	if p == "" {
+		if n.pattern != "" {
+			return buf
+		}
+		return nil
+	}
+	for _, child := range n.children {
+		if len(p) >= len(child.prefix) && strings.EqualFold(p[:len(child.prefix)], child.prefix) {
+			if fixed := child.findCaseInsensitive(p[len(child.prefix):], append(buf, child.prefix...)); fixed != nil {
+				return fixed
+			}
+		}
+	}
+	if len(n.params) > 0 {
+		end := strings.IndexByte(p, '/')
+		if end < 0 {
+			end = len(p)
+		}
+		for _, child := range n.params {
+			if end == 0 || (child.match != nil && !child.match(p[:end])) {
+				continue
+			}
+			if fixed := child.findCaseInsensitive(p[end:], append(buf, p[:end]...)); fixed != nil {
+				return fixed
+			}
+		}
+	}
+	if child := n.anyChild; child != nil && child.pattern != "" {
+		return append(buf, p...)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	cases := map[string]string{
+		"":              "/",
+		"a/b":           "/a/b",
+		"/a//b":         "/a/b",
+		"/a/b/":         "/a/b/",
+		"/a/./b/../c/":  "/a/c/",
+		"/../a":         "/a",
+		"//evil.com/x/": "/evil.com/x/",
+	}
+	for in, want := range cases {
+		if got := cleanPath(in); got != want {
+			t.Fatalf("cleanPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFindCaseInsensitive(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/Users/new", nil)
+	r.addRoute("GET", "/Users/:id/Edit", nil)
+	r.addRoute("GET", "/Static/*filepath", nil)
+
+	cases := map[string]string{
+		"/users/NEW":          "/Users/new",
+		"/USERS/Tiam/edit":    "/Users/Tiam/Edit",
+		"/static/CSS/App.css": "/Static/CSS/App.css",
+		"/users/new/edit":     "/Users/new/Edit",
+	}
+	for path, want := range cases {
+		got := r.roots["GET"].findCaseInsensitive(path, nil)
+		if string(got) != want {
+			t.Fatalf("%s: got %q, want %q", path, got, want)
+		}
+	}
+	if got := r.roots["GET"].findCaseInsensitive("/users/1/remove", nil); got != nil {
+		t.Fatalf("unexpected match %q", got)
+	}
+}
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	r := New()
+	r.GET("/users", func(c *Context) {})
+	r.POST("/posts/", func(c *Context) {})
+
+	cases := []struct {
+		method, target string
+		code           int
+		location       string
+	}{
+		{"GET", "/users/?page=2", http.StatusMovedPermanently, "/users?page=2"},
+		// HEAD 与正常分发一样使用 GET 路由
+		{"HEAD", "/users/", http.StatusMovedPermanently, "/users"},
+		{"POST", "/posts", http.StatusPermanentRedirect, "/posts/"},
+		{"GET", "/users//", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, nil))
+		if w.Code != tc.code || w.Header().Get("Location") != tc.location {
+			t.Fatalf("%s %s: unexpected response %d %q", tc.method, tc.target, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	r.RedirectTrailingSlash = false
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("redirect should be disabled, got %d", w.Code)
+	}
+}
+
+func TestRedirectFixedPath(t *testing.T) {
+	r := New()
+	r.RedirectFixedPath = true
+	r.GET("/Users/:id", func(c *Context) {})
+	r.GET("/docs/", func(c *Context) {})
+	r.Host("api.example.com").GET("/Items", func(c *Context) {})
+
+	cases := []struct {
+		host, target string
+		code         int
+		location     string
+	}{
+		{"", "/users/Tiam", http.StatusMovedPermanently, "/Users/Tiam"},
+		{"", "/a/../Users//7?x=1", http.StatusMovedPermanently, "/Users/7?x=1"},
+		{"", "//DOCS", http.StatusMovedPermanently, "/docs/"},
+		{"", "/Users/7", http.StatusOK, ""},
+		{"", "/unknown", http.StatusNotFound, ""},
+		{"api.example.com", "/items", http.StatusMovedPermanently, "/Items"},
+		{"www.example.com", "/items", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		for _, method := range []string{"GET", "HEAD"} {
+			req := httptest.NewRequest(method, tc.target, nil)
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.code || w.Header().Get("Location") != tc.location {
+				t.Fatalf("%s %s%s: unexpected response %d %q", method, tc.host, tc.target, w.Code, w.Header().Get("Location"))
+			}
+		}
+	}
+}
+
+func TestUseRawPath(t *testing.T) {
+	r := New()
+	r.GET("/files/:name", func(c *Context) {
+		c.String(http.StatusOK, "%s", c.Param("name"))
+	})
+
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/files/a%2Fb%20c", nil))
+		return w
+	}
+	// 默认按解码后的路径匹配, %2F 被当作分隔符
+	if w := request(); w.Code != http.StatusNotFound {
+		t.Fatalf("expect 404, got %d %q", w.Code, w.Body.String())
+	}
+
+	r.UseRawPath = true
+	if w := request(); w.Code != http.StatusOK || w.Body.String() != "a/b c" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	r.UnescapePathValues = false
+	if w := request(); w.Code != http.StatusOK || w.Body.String() != "a%2Fb%20c" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
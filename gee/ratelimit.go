@@ -0,0 +1,259 @@
+package gee
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitResult 一次限流判断的结果
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // 配额完全恢复(令牌桶)或当前窗口结束(滑动窗口)还需的时间
+	RetryAfter time.Duration // 被拒绝时, 下一次请求可能被允许还需等待的时间
+}
+
+// Limiter 限流算法, 可以实现该接口接入 Redis 等外部存储
+type Limiter interface {
+	Allow(key string, now time.Time) RateLimitResult
+}
+
+// RateLimitConfig 限流中间件配置
+type RateLimitConfig struct {
+	Limiter      Limiter                 // 必填, 例如 NewTokenBucket(100, time.Minute)
+	KeyFunc      func(c *Context) string // 限流的维度, 默认 KeyByClientIP
+	Skip         func(c *Context) bool   // 返回 true 时不限流, 例如内网请求
+	ErrorHandler HandlerFunc             // 被限流时调用, 默认返回 429
+}
+
+// KeyByClientIP 按客户端 IP 限流
+func KeyByClientIP(c *Context) string {
+	return c.ClientIP()
+}
+
+// KeyByHeader 按请求头限流, 例如 API Key, 请求头缺失时退化为客户端 IP
+func KeyByHeader(name string) func(c *Context) string {
+	return func(c *Context) string {
+		if v := c.Req.Header.Get(name); v != "" {
+			return name + ":" + v
+		}
+		return c.ClientIP()
+	}
+}
+
+// KeyByRoute 按路由 + 客户端 IP 限流, 每个路由的配额相互独立
+func KeyByRoute(c *Context) string {
+	return c.Method + " " + c.FullPath() + " " + c.ClientIP()
+}
+
+// RateLimit 限流中间件, 响应中设置 X-RateLimit-Limit / Remaining / Reset, 被拒绝时设置 Retry-After 并返回 429
+func RateLimit(conf RateLimitConfig) HandlerFunc {
+	if conf.Limiter == nil {
+		panic("gee: RateLimitConfig.Limiter is required")
+	}
+	if conf.KeyFunc == nil {
+		conf.KeyFunc = KeyByClientIP
+	}
+	if conf.ErrorHandler == nil {
+		conf.ErrorHandler = func(c *Context) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, H{"error": "too many requests"})
+		}
+	}
+
+	return func(c *Context) {
+		if conf.Skip != nil && conf.Skip(c) {
+			c.Next()
+			return
+		}
+		res := conf.Limiter.Allow(conf.KeyFunc(c), time.Now())
+		h := c.W.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(res.Reset), 10))
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.FormatInt(ceilSeconds(res.RetryAfter), 10))
+			conf.ErrorHandler(c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// 向上取整到秒, 避免客户端在配额恢复前重试
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
+
+const limiterShards = 64
+
+// 分片的内存存储, 每个分片一把锁以减少竞争;
+// 超过 ttl 未访问的 key 在该分片下一次清理时删除, 不需要后台协程
+type limiterStore struct {
+	ttl    time.Duration
+	shards [limiterShards]limiterShard
+}
+
+type limiterShard struct {
+	mu        sync.Mutex
+	entries   map[string]*limiterEntry
+	lastSweep time.Time
+}
+
+type limiterEntry struct {
+	lastSeen time.Time
+	// 令牌桶
+	tokens float64
+	// 滑动窗口
+	windowStart time.Time
+	prev, curr  int
+}
+
+func newLimiterStore(ttl time.Duration) *limiterStore {
+	s := &limiterStore{ttl: ttl}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]*limiterEntry)
+	}
+	return s
+}
+
+// 在持有分片锁的情况下调用 fn, fresh 表示 key 是第一次出现(或已过期)
+func (s *limiterStore) with(key string, now time.Time, fn func(e *limiterEntry, fresh bool) RateLimitResult) RateLimitResult {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	shard := &s.shards[h.Sum32()%limiterShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if now.Sub(shard.lastSweep) > s.ttl {
+		for k, e := range shard.entries {
+			if now.Sub(e.lastSeen) > s.ttl {
+				delete(shard.entries, k)
+			}
+		}
+		shard.lastSweep = now
+	}
+	e, ok := shard.entries[key]
+	fresh := !ok || now.Sub(e.lastSeen) > s.ttl
+	if !ok {
+		e = &limiterEntry{}
+		shard.entries[key] = e
+	}
+	res := fn(e, fresh)
+	e.lastSeen = now
+	return res
+}
+
+// 当前保存的 key 数量, 用于测试
+func (s *limiterStore) len() int {
+	n := 0
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+		n += len(s.shards[i].entries)
+		s.shards[i].mu.Unlock()
+	}
+	return n
+}
+
+type tokenBucket struct {
+	limit int
+	rate  float64 // 每秒补充的令牌数
+	store *limiterStore
+}
+
+// NewTokenBucket 令牌桶限流, 桶容量为 limit, 每 window 补满一次, 允许 limit 大小的突发请求
+func NewTokenBucket(limit int, window time.Duration) Limiter {
+	if limit <= 0 || window <= 0 {
+		panic("gee: rate limit and window must be positive")
+	}
+	// 空桶经过 window 后补满, 此后的状态与新建的桶相同, 可以安全删除
+	return &tokenBucket{limit: limit, rate: float64(limit) / window.Seconds(), store: newLimiterStore(window)}
+}
+
+func (b *tokenBucket) Allow(key string, now time.Time) RateLimitResult {
+	return b.store.with(key, now, func(e *limiterEntry, fresh bool) RateLimitResult {
+		capacity := float64(b.limit)
+		if fresh {
+			e.tokens = capacity
+		} else {
+			e.tokens = math.Min(capacity, e.tokens+now.Sub(e.lastSeen).Seconds()*b.rate)
+		}
+		res := RateLimitResult{Limit: b.limit}
+		if e.tokens >= 1 {
+			e.tokens--
+			res.Allowed = true
+		} else {
+			res.RetryAfter = b.duration(1 - e.tokens)
+		}
+		res.Remaining = int(e.tokens)
+		res.Reset = b.duration(capacity - e.tokens)
+		return res
+	})
+}
+
+func (b *tokenBucket) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / b.rate * float64(time.Second))
+}
+
+type slidingWindow struct {
+	limit  int
+	window time.Duration
+	store  *limiterStore
+}
+
+// NewSlidingWindow 滑动窗口限流, 任意 window 长度的时间段内最多 limit 个请求;
+// 使用前一个窗口的计数按时间加权估算, 每个 key 只占用常数空间
+func NewSlidingWindow(limit int, window time.Duration) Limiter {
+	if limit <= 0 || window <= 0 {
+		panic("gee: rate limit and window must be positive")
+	}
+	return &slidingWindow{limit: limit, window: window, store: newLimiterStore(2 * window)}
+}
+
+func (w *slidingWindow) Allow(key string, now time.Time) RateLimitResult {
+	return w.store.with(key, now, func(e *limiterEntry, fresh bool) RateLimitResult {
+		start := now.Truncate(w.window)
+		switch {
+		case fresh || start.Sub(e.windowStart) >= 2*w.window:
+			e.prev, e.curr = 0, 0
+		case start.After(e.windowStart):
+			e.prev, e.curr = e.curr, 0
+		}
+		e.windowStart = start
+
+		elapsed := now.Sub(start)
+		weight := 1 - float64(elapsed)/float64(w.window)
+		count := float64(e.prev)*weight + float64(e.curr)
+		res := RateLimitResult{Limit: w.limit, Reset: w.window - elapsed}
+		if count+1 <= float64(w.limit) {
+			e.curr++
+			res.Allowed = true
+			res.Remaining = int(float64(w.limit) - count - 1)
+		} else {
+			res.RetryAfter = w.retryAfter(e, elapsed)
+		}
+		return res
+	})
+}
+
+// 计算估算值降到 limit-1 以下所需的时间
+func (w *slidingWindow) retryAfter(e *limiterEntry, elapsed time.Duration) time.Duration {
+	win := float64(w.window)
+	room := float64(w.limit - 1)
+	// 当前窗口内随着前一个窗口的权重下降即可放行
+	if e.prev > 0 && float64(e.curr) <= room {
+		at := win * (1 - (room-float64(e.curr))/float64(e.prev))
+		return time.Duration(at) - elapsed
+	}
+	// 需要等到下一个窗口, 此时当前窗口的计数成为加权的前一个窗口
+	at := win * (1 - room/float64(e.curr))
+	return w.window - elapsed + time.Duration(at)
+}
@@ -0,0 +1,100 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	l := NewTokenBucket(2, time.Second)
+	now := time.Unix(1000, 0)
+	for i := 0; i < 2; i++ {
+		if res := l.Allow("a", now); !res.Allowed || res.Remaining != 1-i {
+			t.Fatalf("request %d: %+v", i, res)
+		}
+	}
+	res := l.Allow("a", now)
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("third request should be limited: %+v", res)
+	}
+	if !l.Allow("b", now).Allowed {
+		t.Fatal("keys should be limited independently")
+	}
+	if !l.Allow("a", now.Add(500*time.Millisecond)).Allowed {
+		t.Fatal("token should be refilled")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	l := NewSlidingWindow(4, time.Minute)
+	start := time.Unix(60*1000, 0)
+	for i := 0; i < 4; i++ {
+		if !l.Allow("a", start.Add(time.Duration(i)*time.Second)).Allowed {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	if l.Allow("a", start.Add(30*time.Second)).Allowed {
+		t.Fatal("fifth request in window should be limited")
+	}
+	// 下一个窗口过去 30s, 前一个窗口的 4 个请求权重为 0.5, 估算值为 2
+	now := start.Add(90 * time.Second)
+	for i := 0; i < 2; i++ {
+		if !l.Allow("a", now).Allowed {
+			t.Fatalf("request %d in next window should be allowed", i)
+		}
+	}
+	res := l.Allow("a", now)
+	if res.Allowed || res.RetryAfter != 15*time.Second {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestLimiterStoreEviction(t *testing.T) {
+	s := newLimiterStore(time.Second)
+	now := time.Unix(1000, 0)
+	noop := func(e *limiterEntry, fresh bool) RateLimitResult { return RateLimitResult{} }
+	for _, key := range []string{"a", "b", "c"} {
+		s.with(key, now, noop)
+	}
+	if s.len() != 3 {
+		t.Fatalf("store len = %d", s.len())
+	}
+	// 每个分片在下一次访问时清理过期的 key
+	for i := 0; i < limiterShards*4; i++ {
+		s.with(strconv.Itoa(i), now.Add(2*time.Second), noop)
+	}
+	if s.len() != limiterShards*4 {
+		t.Fatalf("expired keys should be evicted, store len = %d", s.len())
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	r := New()
+	api := r.Group("/api")
+	api.Use(RateLimit(RateLimitConfig{Limiter: NewTokenBucket(1, time.Minute), KeyFunc: KeyByRoute}))
+	api.GET("/a", func(c *Context) { c.String(http.StatusOK, "a") })
+	api.GET("/b/:id", func(c *Context) { c.String(http.StatusOK, "b") })
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	if w := get("/api/a"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "0" ||
+		w.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("first request: %d %v", w.Code, w.Header())
+	}
+	if w := get("/api/a"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("second request: %d %v", w.Code, w.Header())
+	}
+	// 同一路由 pattern 共享配额
+	if w := get("/api/b/1"); w.Code != http.StatusOK {
+		t.Fatalf("other route should have its own quota: %d", w.Code)
+	}
+	if w := get("/api/b/2"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("same route pattern should share quota: %d", w.Code)
+	}
+}
@@ -1,37 +1,216 @@
 package gee
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/http/httputil"
+	"os"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 )
 
-// print stack trace for debug
-func trace(message string) string {
-	var pcs [32]uintptr
-	n := runtime.Callers(3, pcs[:]) // skip first 3 caller
+// RecoveryFunc 处理 panic 的函数, err 为 recover() 的返回值
+type RecoveryFunc func(c *Context, err interface{})
 
-	var str strings.Builder
-	str.WriteString(message + "\nTraceback:")
-	for _, pc := range pcs[:n] {
-		fn := runtime.FuncForPC(pc)
-		file, line := fn.FileLine(pc)
-		str.WriteString(fmt.Sprintf("\n\t%s:%d", file, line))
-	}
-	return str.String()
+// RecoveryConfig panic 恢复配置, 零值即为默认配置
+type RecoveryConfig struct {
+	Output        io.Writer    // 日志写入的位置, 默认为标准库 log 的输出
+	Handler       RecoveryFunc // 生成响应, 默认返回 500; 响应头已发送或连接已断开时不会调用
+	JSON          bool         // 以单行 JSON 输出 PanicReport, 便于错误收集系统解析
+	RedactHeaders []string     // 日志中隐藏值的请求头, 默认 Authorization、Cookie 等
+}
+
+// StackFrame 调用栈的一帧
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
 }
 
+// PanicReport 一次 panic 的详细信息
+type PanicReport struct {
+	Time        time.Time           `json:"time"`
+	Error       string              `json:"error"`
+	Type        string              `json:"type"`
+	BrokenPipe  bool                `json:"broken_pipe,omitempty"`
+	Method      string              `json:"method"`
+	Path        string              `json:"path"`
+	Query       string              `json:"query,omitempty"`
+	ClientIP    string              `json:"client_ip"`
+	RequestID   string              `json:"request_id,omitempty"`
+	Headers     map[string][]string `json:"headers,omitempty"`
+	Stack       []StackFrame        `json:"stack"`
+	HeadersSent bool                `json:"headers_sent,omitempty"`
+}
+
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Csrf-Token"}
+
 func Recovery() HandlerFunc {
+	return RecoveryWithConfig(RecoveryConfig{})
+}
+
+// CustomRecovery 使用自定义的响应函数, 例如返回统一格式的 JSON 错误
+func CustomRecovery(handle RecoveryFunc) HandlerFunc {
+	return RecoveryWithConfig(RecoveryConfig{Handler: handle})
+}
+
+func defaultRecoveryHandler(c *Context, _ interface{}) {
+	c.String(http.StatusInternalServerError, "Internal Server Error")
+}
+
+// RecoveryWithConfig 恢复 handler 中的 panic, 记录调用栈和脱敏后的请求
+func RecoveryWithConfig(conf RecoveryConfig) HandlerFunc {
+	if conf.Handler == nil {
+		conf.Handler = defaultRecoveryHandler
+	}
+	if conf.RedactHeaders == nil {
+		conf.RedactHeaders = defaultRedactHeaders
+	}
+	redact := make(map[string]bool, len(conf.RedactHeaders))
+	for _, h := range conf.RedactHeaders {
+		redact[http.CanonicalHeaderKey(h)] = true
+	}
+	var mu sync.Mutex
+	output := func(s string) {
+		if conf.Output == nil {
+			log.Print(s)
+			return
+		}
+		mu.Lock()
+		_, _ = io.WriteString(conf.Output, s)
+		mu.Unlock()
+	}
+
 	return func(c *Context) {
 		defer func() {
-			if err := recover(); err != nil {
-				message := fmt.Sprintf("%s", err)
-				log.Printf("%s\n\n", trace(message))
-				c.String(http.StatusInternalServerError, "Internal Server Error")
+			err := recover()
+			if err == nil {
+				return
+			}
+			// net/http 用来中断响应的 panic, 交给 net/http 处理
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			report := newPanicReport(c, err, redact)
+			if conf.JSON {
+				data, _ := json.Marshal(report)
+				output(string(data) + "\n")
+			} else {
+				output(report.text(c.Req))
+			}
+
+			if report.BrokenPipe || report.HeadersSent {
+				// 连接已断开或状态码已发送, 无法再写出有意义的响应
+				c.Abort()
+				return
 			}
+			conf.Handler(c, err)
+			c.Abort()
 		}()
 		c.Next()
 	}
 }
+
+func newPanicReport(c *Context, err interface{}, redact map[string]bool) *PanicReport {
+	return &PanicReport{
+		Time:        time.Now(),
+		Error:       fmt.Sprintf("%v", err),
+		Type:        fmt.Sprintf("%T", err),
+		BrokenPipe:  isBrokenPipe(err),
+		Method:      c.Method,
+		Path:        c.Path,
+		Query:       c.Req.URL.RawQuery,
+		ClientIP:    c.ClientIP(),
+		RequestID:   c.GetString(RequestIDKey),
+		Headers:     redactHeaders(c.Req.Header, redact),
+		Stack:       stack(4),
+		HeadersSent: c.W.Written(),
+	}
+}
+
+// 客户端断开连接后写响应会得到 broken pipe 或 connection reset
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(e, &opErr) {
+		return false
+	}
+	var sysErr *os.SyscallError
+	if !errors.As(opErr, &sysErr) {
+		return false
+	}
+	msg := strings.ToLower(sysErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
+func redactHeaders(header http.Header, redact map[string]bool) map[string][]string {
+	headers := make(map[string][]string, len(header))
+	for k, v := range header {
+		if redact[k] {
+			headers[k] = []string{"[REDACTED]"}
+		} else {
+			headers[k] = v
+		}
+	}
+	return headers
+}
+
+// 返回完整的调用栈, skip 为跳过的栈帧数
+func stack(skip int) []StackFrame {
+	pcs := make([]uintptr, 64)
+	for {
+		n := runtime.Callers(skip, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, len(pcs)*2)
+	}
+	frames := runtime.CallersFrames(pcs)
+	var result []StackFrame
+	for {
+		frame, more := frames.Next()
+		result = append(result, StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		if !more {
+			break
+		}
+	}
+	return result
+}
+
+// 文本格式: panic 信息、脱敏后的请求和调用栈
+func (r *PanicReport) text(req *http.Request) string {
+	var b strings.Builder
+	if r.BrokenPipe {
+		b.WriteString("[Recovery] broken pipe: ")
+	} else {
+		b.WriteString("[Recovery] panic recovered: ")
+	}
+	b.WriteString(r.Error)
+	b.WriteString("\n")
+	clone := req.Clone(req.Context())
+	clone.Header = r.Headers
+	clone.Body = nil
+	if dump, err := httputil.DumpRequest(clone, false); err == nil {
+		b.Write(dump)
+	}
+	if r.BrokenPipe {
+		return b.String()
+	}
+	b.WriteString("Traceback:")
+	for _, f := range r.Stack {
+		fmt.Fprintf(&b, "\n\t%s\n\t\t%s:%d", f.Function, f.File, f.Line)
+	}
+	b.WriteString("\n\n")
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(RecoveryWithConfig(RecoveryConfig{Output: &buf}))
+	r.GET("/panic", func(c *Context) {
+		var names []string
+		c.String(http.StatusOK, names[1])
+	})
+	r.GET("/partial", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("after write")
+	})
+
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "Internal Server Error" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	log := buf.String()
+	if !strings.Contains(log, "index out of range") || !strings.Contains(log, "recovery_test.go") ||
+		!strings.Contains(log, "Authorization: [REDACTED]") || strings.Contains(log, "secret-token") {
+		t.Fatalf("unexpected log %s", log)
+	}
+
+	// 响应头已发送, 不能再写入 500
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/partial", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestCustomRecoveryJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(RecoveryWithConfig(RecoveryConfig{
+		Output: &buf,
+		JSON:   true,
+		Handler: func(c *Context, err interface{}) {
+			c.JSON(http.StatusInternalServerError, H{"error": err})
+		},
+	}))
+	r.GET("/panic", func(c *Context) { panic(H{"code": 42}) })
+
+	req := httptest.NewRequest("GET", "/panic?a=1", nil)
+	req.Header.Set("Cookie", "session=secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"code":42`) {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	var report PanicReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Type != "gee.H" || report.Error != "map[code:42]" || report.Query != "a=1" ||
+		report.Headers["Cookie"][0] != "[REDACTED]" || len(report.Stack) == 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	brokenPipe := &net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)}
+	if !isBrokenPipe(brokenPipe) || isBrokenPipe("broken pipe") {
+		t.Fatal("isBrokenPipe mismatch")
+	}
+
+	var buf bytes.Buffer
+	r := New()
+	r.Use(RecoveryWithConfig(RecoveryConfig{Output: &buf, Handler: func(c *Context, err interface{}) {
+		t.Error("handler shouldn't be called for broken pipe")
+	}}))
+	r.GET("/", func(c *Context) { panic(brokenPipe) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !strings.HasPrefix(buf.String(), "[Recovery] broken pipe") || w.Body.Len() != 0 {
+		t.Fatalf("unexpected log %q, body %q", buf.String(), w.Body.String())
+	}
+}
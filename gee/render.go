@@ -0,0 +1,280 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MIMEHTML     = "text/html"
+	MIMEPlain    = "text/plain"
+	MIMEXML      = "application/xml"
+	MIMEXML2     = "text/xml"
+	MIMEYAML     = "application/x-yaml"
+	MIMEJSONP    = "application/javascript"
+	MIMEPROTOBUF = "application/x-protobuf"
+)
+
+// ProtoMarshal 用于 ProtoBuf 渲染的序列化函数, 为了不引入依赖由使用方设置, 例如:
+//
+//	gee.ProtoMarshal = func(obj interface{}) ([]byte, error) { return proto.Marshal(obj.(proto.Message)) }
+//
+// 未设置时要求 obj 实现 Marshal() ([]byte, error)
+var ProtoMarshal func(obj interface{}) ([]byte, error)
+
+// 写入已编码的响应体, 编码出错时返回 500
+func (c *Context) render(code int, contentType string, data []byte, err error) {
+	if err != nil {
+		c.Error(err).SetType(ErrorTypeRender)
+		return
+	}
+	c.SetHeader("Content-Type", contentType)
+	c.Status(code)
+	if c.Method != http.MethodHead && bodyAllowedForStatus(code) {
+		c.W.Write(data)
+	}
+}
+
+// 1xx、204、304 不允许有响应体
+func bodyAllowedForStatus(code int) bool {
+	switch {
+	case code >= 100 && code <= 199, code == http.StatusNoContent, code == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// IndentedJSON 返回缩进格式的 JSON, 便于调试阅读
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	data, err := json.MarshalIndent(obj, "", "    ")
+	c.render(code, MIMEJSON+"; charset=utf-8", data, err)
+}
+
+// SecureJSON 在 JSON 数组前加上 Engine 的 secureJSONPrefix, 防止 JSON 劫持
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err == nil && bytes.HasPrefix(data, []byte("[")) && bytes.HasSuffix(data, []byte("]")) {
+		data = append([]byte(c.engine.secureJSONPrefix), data...)
+	}
+	c.render(code, MIMEJSON+"; charset=utf-8", data, err)
+}
+
+// JSONP 使用 query 中的 callback 包装 JSON, 没有 callback 时等同于 JSON
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback == "" {
+		c.JSON(code, obj)
+		return
+	}
+	data, err := json.Marshal(obj)
+	if err == nil {
+		callback = template.JSEscapeString(callback)
+		data = []byte(callback + "(" + string(data) + ");")
+	}
+	c.render(code, MIMEJSONP+"; charset=utf-8", data, err)
+}
+
+// AsciiJSON 将非 ASCII 字符转义为 \uXXXX
+func (c *Context) AsciiJSON(code int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err == nil {
+		var buf bytes.Buffer
+		for _, r := range string(data) {
+			if r < utf8.RuneSelf {
+				buf.WriteRune(r)
+			} else if r > 0xFFFF {
+				// 超出 BMP 的字符使用 UTF-16 代理对表示
+				r -= 0x10000
+				fmt.Fprintf(&buf, `\u%04x\u%04x`, 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			} else {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			}
+		}
+		data = buf.Bytes()
+	}
+	c.render(code, MIMEJSON, data, err)
+}
+
+func (c *Context) XML(code int, obj interface{}) {
+	data, err := xml.Marshal(obj)
+	c.render(code, MIMEXML+"; charset=utf-8", data, err)
+}
+
+// YAML 按 json tag 将 obj 渲染为 YAML
+func (c *Context) YAML(code int, obj interface{}) {
+	data, err := marshalYAML(obj)
+	c.render(code, MIMEYAML+"; charset=utf-8", data, err)
+}
+
+func (c *Context) ProtoBuf(code int, obj interface{}) {
+	var data []byte
+	var err error
+	if ProtoMarshal != nil {
+		data, err = ProtoMarshal(obj)
+	} else if m, ok := obj.(interface{ Marshal() ([]byte, error) }); ok {
+		data, err = m.Marshal()
+	} else {
+		err = errors.New("gee: ProtoMarshal is not set and object does not implement Marshal")
+	}
+	c.render(code, MIMEPROTOBUF, data, err)
+}
+
+// Redirect 重定向到 location, code 必须是 3xx 或 201
+func (c *Context) Redirect(code int, location string) {
+	if (code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect) && code != http.StatusCreated {
+		panic(fmt.Sprintf("gee: cannot redirect with status code %d", code))
+	}
+	http.Redirect(c.W, c.Req, location, code)
+}
+
+// File 返回文件内容, 支持 Range 和 If-Modified-Since
+func (c *Context) File(filepath string) {
+	http.ServeFile(c.W, c.Req, filepath)
+}
+
+// FileAttachment 以附件形式返回文件, 浏览器会以 filename 保存
+func (c *Context) FileAttachment(filepath string, filename string) {
+	if isASCII(filename) {
+		c.SetHeader("Content-Disposition", `attachment; filename="`+strings.ReplaceAll(filename, `"`, `\"`)+`"`)
+	} else {
+		c.SetHeader("Content-Disposition", `attachment; filename*=UTF-8''`+url.QueryEscape(filename))
+	}
+	http.ServeFile(c.W, c.Req, filepath)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Stream 持续调用 step 写入响应并刷新, step 返回 false 或客户端断开时结束
+// 返回 true 表示客户端提前断开
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.W)
+			c.W.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// Negotiate 内容协商的候选数据, 按 Offered 的顺序与 Accept 匹配
+type Negotiate struct {
+	Offered  []string // 可提供的 MIME 类型, 例如 MIMEJSON, MIMEXML
+	HTMLName string
+	HTMLData interface{}
+	JSONData interface{}
+	XMLData  interface{}
+	YAMLData interface{}
+	Data     interface{} // 对应格式的数据未设置时使用
+}
+
+// Negotiate 根据 Accept 选择响应格式, 没有可接受的格式时返回 406
+func (c *Context) Negotiate(code int, config Negotiate) {
+	pick := func(data interface{}) interface{} {
+		if data != nil {
+			return data
+		}
+		return config.Data
+	}
+	switch c.NegotiateFormat(config.Offered...) {
+	case MIMEJSON:
+		c.JSON(code, pick(config.JSONData))
+	case MIMEHTML:
+		c.HTML(code, config.HTMLName, pick(config.HTMLData))
+	case MIMEXML, MIMEXML2:
+		c.XML(code, pick(config.XMLData))
+	case MIMEYAML:
+		c.YAML(code, pick(config.YAMLData))
+	case MIMEPlain:
+		c.String(code, "%v", config.Data)
+	default:
+		c.String(http.StatusNotAcceptable, "406 NOT ACCEPTABLE: %s\n", c.Req.Header.Get("Accept"))
+	}
+}
+
+// NegotiateFormat 返回 offered 中最符合 Accept 的类型, 没有 Accept 时返回第一个
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		panic("gee: you must provide at least one offer")
+	}
+	accepted := parseAccept(c.Req.Header.Get("Accept"))
+	if len(accepted) == 0 {
+		return offered[0]
+	}
+	for _, accept := range accepted {
+		for _, offer := range offered {
+			if mimeMatch(accept, offer) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// 解析 Accept 头, 按 q 值从高到低排序, 去掉 q=0 的类型
+func parseAccept(header string) []string {
+	type item struct {
+		mime string
+		q    float64
+	}
+	var items []item
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mime := strings.TrimSpace(fields[0])
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			items = append(items, item{mime, q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	mimes := make([]string, len(items))
+	for i, it := range items {
+		mimes[i] = it.mime
+	}
+	return mimes
+}
+
+// 支持 */* 与 text/* 形式的通配
+func mimeMatch(accept string, offer string) bool {
+	if accept == "*/*" || accept == offer {
+		return true
+	}
+	if strings.HasSuffix(accept, "/*") {
+		return strings.HasPrefix(offer, accept[:len(accept)-1])
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type renderUser struct {
+	Name  string   `json:"name" xml:"name"`
+	Tags  []string `json:"tags" xml:"tag"`
+	Admin bool     `json:"admin" xml:"admin"`
+}
+
+func serveRender(r *Engine, target string, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRenderers(t *testing.T) {
+	user := renderUser{Name: "天马", Tags: []string{"go", "yes"}}
+	r := New()
+	r.GET("/xml", func(c *Context) { c.XML(http.StatusOK, user) })
+	r.GET("/yaml", func(c *Context) { c.YAML(http.StatusOK, H{"user": user, "empty": []int{}}) })
+	r.GET("/ascii", func(c *Context) { c.AsciiJSON(http.StatusOK, user) })
+	r.GET("/secure", func(c *Context) { c.SecureJSON(http.StatusOK, []int{1, 2}) })
+	r.GET("/jsonp", func(c *Context) { c.JSONP(http.StatusOK, H{"a": 1}) })
+	r.GET("/redirect", func(c *Context) { c.Redirect(http.StatusFound, "/xml") })
+
+	cases := []struct {
+		target string
+		body   string
+	}{
+		{"/xml", "<renderUser><name>天马</name><tag>go</tag><tag>yes</tag><admin>false</admin></renderUser>"},
+		{"/yaml", "empty: []\nuser:\n  name: 天马\n  tags:\n    - go\n    - \"yes\"\n  admin: false\n"},
+		{"/ascii", `{"name":"\u5929\u9a6c","tags":["go","yes"],"admin":false}`},
+		{"/secure", "while(1);[1,2]"},
+		{"/jsonp?callback=cb", `cb({"a":1});`},
+	}
+	for _, tc := range cases {
+		w := serveRender(r, tc.target, "")
+		if w.Code != http.StatusOK || w.Body.String() != tc.body {
+			t.Fatalf("%s: unexpected response %d %q", tc.target, w.Code, w.Body.String())
+		}
+	}
+
+	w := serveRender(r, "/redirect", "")
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/xml" {
+		t.Fatalf("unexpected redirect %d %s", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	r := New()
+	r.GET("/user", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			Offered: []string{MIMEJSON, MIMEXML, MIMEYAML},
+			Data:    renderUser{Name: "tiam"},
+		})
+	})
+
+	cases := map[string]string{
+		"":                                    MIMEJSON,
+		"application/xml":                     MIMEXML,
+		"text/html, application/x-yaml;q=0.9": MIMEYAML,
+		"application/json;q=0.5, application/xml": MIMEXML,
+	}
+	for accept, mime := range cases {
+		w := serveRender(r, "/user", accept)
+		if ct := w.Header().Get("Content-Type"); len(ct) < len(mime) || ct[:len(mime)] != mime {
+			t.Fatalf("Accept %q: expect %s, got %s", accept, mime, ct)
+		}
+	}
+	if w := serveRender(r, "/user", "image/png"); w.Code != http.StatusNotAcceptable {
+		t.Fatalf("expect 406, got %d", w.Code)
+	}
+}
@@ -0,0 +1,118 @@
+package gee
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+const noWritten = -1
+
+// ResponseWriter 在 http.ResponseWriter 的基础上记录状态码和写入的字节数
+// 状态码延迟到第一次写入响应体(或请求结束)时才真正发送, 在此之前仍可修改响应头
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+
+	Status() int     // 响应码, 未设置时为 200
+	Size() int       // 已写入响应体的字节数, 未写入时为 -1
+	Written() bool   // 响应头是否已经发送
+	WriteHeaderNow() // 立即发送响应头
+	WriteString(s string) (int, error)
+	Before(fn func()) // 注册在响应头发送前调用的函数, 例如写入会话 cookie
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+	before []func()
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = http.StatusOK
+	w.size = noWritten
+	w.before = w.before[:0]
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && w.status != code && !w.Written() {
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		// 按注册的相反顺序调用, 与 defer 一致
+		for i := len(w.before) - 1; i >= 0; i-- {
+			w.before[i]()
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Before(fn func()) {
+	w.before = append(w.before, fn)
+}
+
+func (w *responseWriter) Write(data []byte) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = w.ResponseWriter.Write(data)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) WriteString(s string) (n int, err error) {
+	w.WriteHeaderNow()
+	if sw, ok := w.ResponseWriter.(interface {
+		WriteString(string) (int, error)
+	}); ok {
+		n, err = sw.WriteString(s)
+	} else {
+		n, err = w.ResponseWriter.Write([]byte(s))
+	}
+	w.size += n
+	return
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 接管底层连接, 之后不再经过 ResponseWriter 写入
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("gee: response writer does not implement http.Hijacker")
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	return hj.Hijack()
+}
+
+// Unwrap 供 http.ResponseController 获取原始的 ResponseWriter
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -2,97 +2,246 @@ package gee
 
 import (
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
+	"net/url"
 	"path"
+	"sort"
 	"strings"
 )
 
+// anyMethods Any 注册时覆盖的请求方法
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete,
+	http.MethodConnect, http.MethodTrace,
+}
+
+// Param 路由参数, 例如 /p/:lang 中的 lang
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params 按路由中出现的顺序保存参数, 用切片代替 map 以避免每次请求分配内存
+type Params []Param
+
+// Get 返回第一个名为 name 的参数值
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// ByName 返回名为 name 的参数值, 不存在时返回空串
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
+}
+
 type router struct {
-	roots    map[string]*node // 每种请求类型单独建一颗trie
-	handlers map[string]HandlerFunc
+	roots     map[string]*node // 每种请求类型单独建一颗radix tree
+	maxParams int              // 所有路由中参数个数的最大值, 用于预分配 Params
+	routes    []*Route         // 按注册顺序保存的所有路由
+	hosts     []*hostRouter    // Engine.Host 注册的路由, 先于不限 Host 的路由匹配
 }
 
 // roots key eg, roots['GET'] roots['POST']
-// handlers key eg, handlers['GET-/p/:lang/doc'], handlers['POST-/p/book']
 
 func newRouter() *router {
 	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
+		roots: make(map[string]*node),
 	}
 }
 
-// 解析 pattern --> parts  只有一个 *xxx 可以被添加
-func parsePattern(pattern string) []string {
-	vs := strings.Split(pattern, "/")
-
-	parts := []string{}
-	for _, item := range vs {
-		if item != "" {
-			parts = append(parts, item)
-			if item[0] == '*' {
-				break
-			}
+// 统计 pattern 中的参数个数
+func countParams(pattern string) int {
+	count := 0
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, ok, _ := parseSegment(seg); ok {
+			count++
 		}
 	}
-	fmt.Println(parts)
-	return parts
+	return count
 }
 
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
+func (r *router) addRoute(method string, pattern string, handlers []HandlerFunc) *node {
+	if pattern == "" || pattern[0] != '/' {
+		panic(fmt.Sprintf("gee: route '%s' must begin with '/'", pattern))
+	}
 	log.Printf("Route %4s - %s", method, pattern)
-	parts := parsePattern(pattern)
 
 	if _, ok := r.roots[method]; !ok {
 		r.roots[method] = &node{}
 	}
-	r.roots[method].insert(pattern, parts, 0)
+	n := r.roots[method].insert(pattern, handlers)
 
-	key := method + "-" + pattern
-	r.handlers[key] = handler
+	if count := countParams(pattern); count > r.maxParams {
+		r.maxParams = count
+	}
+	return n
 }
 
-func (r *router) getRoute(method string, pattern string) (*node, map[string]string) {
-	searchParts := parsePattern(pattern)
-	//fmt.Println("getRoute: ", searchParts)
+// 查找路由, 参数写入 params, params 由调用方复用
+func (r *router) search(method string, path string, params *Params) *node {
 	root, ok := r.roots[method]
 	if !ok {
+		return nil
+	}
+	return root.search(path, params)
+}
+
+func (r *router) getRoute(method string, path string) (*node, Params) {
+	params := make(Params, 0, r.maxParams)
+	target := r.search(method, path, &params)
+	if target == nil {
 		return nil, nil
 	}
+	return target, params
+}
 
-	params := map[string]string{}
-	target := root.search(searchParts, 0)
-	if target != nil {
-		// 解析 : 和 * 两种匹配符的参数
-		parts := parsePattern(target.pattern)
-		for index, part := range parts {
-			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
-			} else if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
-				break
+// 按请求方法查找路由, 未注册 HEAD 时复用 GET 路由, 响应体由 net/http 丢弃
+func (r *router) lookup(method string, path string, params *Params) *node {
+	base := len(*params)
+	target := r.search(method, path, params)
+	if target == nil && method == http.MethodHead {
+		*params = (*params)[:base]
+		target = r.search(http.MethodGet, path, params)
+	}
+	return target
+}
+
+// 合并 Host 下的路由和不限 Host 的路由计算 Allow, 两者都匹配时执行 Host 下路由所在组的中间件
+func (r *router) allowedFor(host *hostRouter, path string) ([]string, *node) {
+	allow, matched := r.allowed(path)
+	if host == nil {
+		return allow, matched
+	}
+	hostAllow, hostMatched := host.router.allowed(path)
+	if hostAllow == nil {
+		return allow, matched
+	}
+	seen := make(map[string]bool, len(hostAllow))
+	for _, method := range hostAllow {
+		seen[method] = true
+	}
+	for _, method := range allow {
+		if !seen[method] {
+			hostAllow = append(hostAllow, method)
+		}
+	}
+	sort.Strings(hostAllow)
+	return hostAllow, hostMatched
+}
+
+// 计算 path 在各个 trie 中能匹配到的方法, 用于 Allow 头, 同时返回其中一个匹配的节点:
+// 优先取 GET, 否则按方法名排序取第一个, 保证自动应答执行的中间件是确定的
+func (r *router) allowed(path string) ([]string, *node) {
+	methods := make([]string, 0, len(r.roots))
+	for method := range r.roots {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	var allow []string
+	var matched *node
+	for _, method := range methods {
+		if target, _ := r.getRoute(method, path); target != nil {
+			allow = append(allow, method)
+			if matched == nil || method == http.MethodGet {
+				matched = target
 			}
 		}
-		return target, params
 	}
-	return nil, nil
+	if len(allow) == 0 {
+		return nil, nil
+	}
+	// GET 自动支持 HEAD, OPTIONS 由框架自动应答
+	seen := make(map[string]bool, len(allow))
+	for _, method := range allow {
+		seen[method] = true
+	}
+	if seen[http.MethodGet] && !seen[http.MethodHead] {
+		allow = append(allow, http.MethodHead)
+	}
+	if !seen[http.MethodOptions] {
+		allow = append(allow, http.MethodOptions)
+	}
+	sort.Strings(allow)
+	return allow, matched
 }
 
 // 处理请求, 路由到真正处理的方法(handler)
 func (r *router) handle(c *Context) {
-	target, params := r.getRoute(c.Method, c.Path)
+	if cap(c.Params) < r.maxParams {
+		c.Params = make(Params, 0, r.maxParams)
+	}
+	c.Params = c.Params[:0]
+	path, unescape := c.Path, false
+	if engine := c.engine; engine != nil && engine.UseRawPath && c.Req.URL.RawPath != "" {
+		// 按转义后的路径匹配, 参数中的 %2F 不会被当作路径分隔符
+		path, unescape = c.Req.URL.RawPath, engine.UnescapePathValues
+	}
+	var host *hostRouter
+	var target *node
+	if len(r.hosts) > 0 {
+		// Host 参数在前, 路径参数在后
+		if host = r.matchHost(c); host != nil {
+			if target = host.router.lookup(c.Method, path, &c.Params); target == nil {
+				c.Params = c.Params[:0]
+			}
+		}
+	}
+	if target == nil {
+		target = r.lookup(c.Method, path, &c.Params)
+	}
 	if target != nil {
-		c.Params = params
-		key := c.Method + "-" + target.pattern
-		c.handlers = append(c.handlers, r.handlers[key])
+		// 路由的处理链在注册时已经确定, 直接复用
+		c.handlers = target.handlers
+		c.fullPath = target.pattern
+		if unescape {
+			for i := range c.Params {
+				if value, err := url.PathUnescape(c.Params[i].Value); err == nil {
+					c.Params[i].Value = value
+				}
+			}
+		}
+	} else if location, ok := r.fixPath(c, host, path); ok {
+		// 修正后的路径可以匹配到路由: GET、HEAD 返回 301, 其他方法返回 308 以保留请求方法和请求体
+		code := http.StatusMovedPermanently
+		if c.Method != http.MethodGet && c.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		c.handlers = c.engine.combineHandlers([]HandlerFunc{func(c *Context) {
+			c.Redirect(code, location)
+		}})
+	} else if allow, matched := r.allowedFor(host, path); allow != nil {
+		// path 存在于其他方法下: OPTIONS 自动应答, 其余返回 405
+		// 执行匹配路由所在路由组的中间件(例如 CORS), 但不执行该路由独有的中间件
+		allowHeader := strings.Join(allow, ", ")
+		middlewares := matched.handlers[:matched.groupSize:matched.groupSize]
+		if c.Method == http.MethodOptions {
+			c.handlers = append(middlewares, func(c *Context) {
+				c.SetHeader("Allow", allowHeader)
+				c.Status(http.StatusNoContent)
+			})
+		} else {
+			c.SetHeader("Allow", allowHeader)
+			noMethod := c.engine.noMethod
+			if len(noMethod) == 0 {
+				noMethod = []HandlerFunc{defaultNoMethod}
+			}
+			c.handlers = append(middlewares, noMethod...)
+		}
 	} else {
-		c.handlers = append(c.handlers, func(c *Context) {
-			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
-		})
+		c.handlers = c.engine.allNoRoute
 	}
 	// 执行当前的函数列表 [middlewares..., handler]
 	c.Next()
+	c.handleErrors()
 }
 
 // RouteGroup 路由组
@@ -101,6 +250,7 @@ type RouteGroup struct {
 	middlewares []HandlerFunc // support middlewares
 	parent      *RouteGroup   // support nesting
 	engine      *Engine       // all groups share an Engine instance
+	host        *hostRouter   // 通过 Engine.Host 创建时只匹配该 Host
 }
 
 // Group is defined to create a new RouterGroup
@@ -111,33 +261,123 @@ func (group *RouteGroup) Group(prefix string) *RouteGroup {
 		prefix: group.prefix + prefix,
 		parent: group,
 		engine: engine,
+		host:   group.host,
 	}
 	engine.groups = append(engine.groups, newGroup)
 	return newGroup
 }
 
-func (group *RouteGroup) addRoute(method string, comp string, handler HandlerFunc) {
+// 注册路由时确定处理链: 各级路由组的中间件 + 路由自身的中间件与处理方法
+func (group *RouteGroup) addRoute(method string, comp string, handlers []HandlerFunc) *Route {
+	if len(handlers) == 0 {
+		panic("gee: there must be at least one handler")
+	}
 	pattern := group.prefix + comp
 	// log.Printf("Route %4s - %s", method, pattern)
-	group.engine.router.addRoute(method, pattern, handler)
+	merged := group.combineHandlers(handlers)
+	r := group.engine.router
+	var n *node
+	if group.host != nil {
+		n = group.host.router.addRoute(method, pattern, merged)
+		// 预分配的 Params 需要同时容纳 Host 参数和路径参数
+		if count := group.host.router.maxParams + group.host.params; count > r.maxParams {
+			r.maxParams = count
+		}
+	} else {
+		n = r.addRoute(method, pattern, merged)
+	}
+	n.groupSize = len(merged) - len(handlers)
+	route := &Route{Method: method, Pattern: pattern, node: n, engine: group.engine}
+	if group.host != nil {
+		route.Host = group.host.pattern
+	}
+	r.routes = append(r.routes, route)
+	return route
+}
+
+// 从根路由组开始依次拼接中间件, 最后追加 handlers
+func (group *RouteGroup) combineHandlers(handlers []HandlerFunc) []HandlerFunc {
+	var groups []*RouteGroup
+	for g := group; g != nil; g = g.parent {
+		groups = append(groups, g)
+	}
+	size := len(handlers)
+	for _, g := range groups {
+		size += len(g.middlewares)
+	}
+	if size >= abortIndex {
+		panic("gee: too many handlers")
+	}
+	merged := make([]HandlerFunc, 0, size)
+	for i := len(groups) - 1; i >= 0; i-- {
+		merged = append(merged, groups[i].middlewares...)
+	}
+	return append(merged, handlers...)
 }
 
-func (group *RouteGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+// Handle 以任意请求方法注册路由, 最后一个为处理方法, 之前的为该路由独有的中间件
+func (group *RouteGroup) Handle(method string, pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(method, pattern, handlers)
 }
 
-func (group *RouteGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
+// pattern 其实就是路径
+func (group *RouteGroup) GET(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodGet, pattern, handlers)
 }
 
-// 添加middleware
+func (group *RouteGroup) POST(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodPost, pattern, handlers)
+}
+
+func (group *RouteGroup) PUT(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodPut, pattern, handlers)
+}
+
+func (group *RouteGroup) PATCH(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodPatch, pattern, handlers)
+}
+
+func (group *RouteGroup) DELETE(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodDelete, pattern, handlers)
+}
+
+func (group *RouteGroup) OPTIONS(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodOptions, pattern, handlers)
+}
+
+func (group *RouteGroup) HEAD(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(http.MethodHead, pattern, handlers)
+}
+
+// Any 为 pattern 注册所有常见请求方法, 按 anyMethods 的顺序返回注册的路由
+func (group *RouteGroup) Any(pattern string, handlers ...HandlerFunc) []*Route {
+	routes := make([]*Route, 0, len(anyMethods))
+	for _, method := range anyMethods {
+		routes = append(routes, group.addRoute(method, pattern, handlers))
+	}
+	return routes
+}
+
+// 添加middleware, 只对之后注册的路由生效
 func (group *RouteGroup) Use(middlewares ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
+	if group == group.engine.RouteGroup {
+		group.engine.rebuild404Handlers()
+	}
 }
 
 //	用户可以将磁盘上的某个文件夹root映射到路由relativePath
 func (group *RouteGroup) Static(relativePath string, root string) {
-	handler := group.createStaticHandler(relativePath, http.Dir(root))
+	group.staticFileSystem(relativePath, http.Dir(root))
+}
+
+// StaticFS 与 Static 相同, 但文件来自 fsys, 例如 embed.FS, 便于打包成单个可执行文件
+func (group *RouteGroup) StaticFS(relativePath string, fsys fs.FS) {
+	group.staticFileSystem(relativePath, http.FS(fsys))
+}
+
+func (group *RouteGroup) staticFileSystem(relativePath string, fs http.FileSystem) {
+	handler := group.createStaticHandler(relativePath, fs)
 	urlPattern := path.Join(relativePath, "/*filepath")
 	group.GET(urlPattern, handler)
 }
@@ -149,10 +389,12 @@ func (group *RouteGroup) createStaticHandler(relativePath string, fs http.FileSy
 	return func(c *Context) {
 		file := c.Param("filepath")
 		// 寻找资源并进行权限检查
-		if _, err := fs.Open(file); err != nil {
+		f, err := fs.Open(file)
+		if err != nil {
 			c.Status(http.StatusNotFound)
 			return
 		}
+		f.Close()
 		fileServer.ServeHTTP(c.W, c.Req)
 	}
 }
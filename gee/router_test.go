@@ -1,41 +1,189 @@
 package gee
 
 import (
-	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 )
 
 func newTestRouter() *router {
 	r := newRouter()
-	//r.addRoute("GET", "/", nil)
-	//r.addRoute("GET", "/hello/:name", nil)
-	//r.addRoute("GET", "/hello/b/c", nil)
-	//r.addRoute("GET", "/hi/:name", nil)
+	r.addRoute("GET", "/", nil)
+	r.addRoute("GET", "/hello/:name", nil)
+	r.addRoute("GET", "/hello/b/c", nil)
+	r.addRoute("GET", "/hi/:name", nil)
 	r.addRoute("GET", "/assets/*filepath", nil)
 	return r
 }
 
-func TestParsePattern(t *testing.T) {
-	ok := reflect.DeepEqual(parsePattern("/p/:name"), []string{"p", ":name"})
-	ok = ok && reflect.DeepEqual(parsePattern("/p/*"), []string{"p", "*"})
-	ok = ok && reflect.DeepEqual(parsePattern("/p/*name/*"), []string{"p", "*name"})
-	if !ok {
-		t.Fatal("test parsePattern failed")
+func TestGetRoute(t *testing.T) {
+	r := newTestRouter()
+	n, ps := r.getRoute("GET", "/hello/geektutu")
+	if n == nil {
+		t.Fatal("nil shouldn't be returned")
+	}
+	if n.pattern != "/hello/:name" {
+		t.Fatal("should match /hello/:name")
+	}
+	if ps.ByName("name") != "geektutu" {
+		t.Fatal("name should be equal to 'geektutu'")
+	}
+
+	n, ps = r.getRoute("GET", "/assets/css/zhangjiyuan.cc")
+	if n == nil || n.pattern != "/assets/*filepath" || ps.ByName("filepath") != "css/zhangjiyuan.cc" {
+		t.Fatal("should match /assets/*filepath with filepath 'css/zhangjiyuan.cc'")
+	}
+
+	cases := map[string]string{
+		"/":          "/",
+		"/hello/b/c": "/hello/b/c",
+		"/hi/tiam":   "/hi/:name",
+		"/hello/b":   "/hello/:name",
+	}
+	for path, pattern := range cases {
+		if n, _ := r.getRoute("GET", path); n == nil || n.pattern != pattern {
+			t.Fatalf("%s should match %s, got %v", path, pattern, n)
+		}
+	}
+	for _, path := range []string{"/hello", "/hello/b/d", "/hi/tiam/x"} {
+		if n, _ := r.getRoute("GET", path); n != nil {
+			t.Fatalf("%s shouldn't match %s", path, n.pattern)
+		}
 	}
 }
 
-func TestGetRoute(t *testing.T) {
-	r := newTestRouter()
-	n, ps := r.getRoute("GET", "/assets/*filepath/zhangjiyuan.cc")
-	//if n == nil {
-	//	t.Fatal("nil shouldn't be returned")
-	//}
-	//if n.pattern != "/hello/:name" {
-	//	t.Fatal("should match /hello/:name")
-	//}
-	//if ps["name"] != "geektutu" {
-	//	t.Fatal("name should be equal to 'geektutu'")
-	//}
-	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps["filepath"])
+func TestRoutePriority(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/users/new", nil)
+	r.addRoute("GET", "/users/:id", nil)
+	r.addRoute("GET", "/users/:id/edit", nil)
+	r.addRoute("GET", "/users/*path", nil)
+
+	cases := map[string]string{
+		"/users/new":      "/users/new",
+		"/users/newer":    "/users/:id",
+		"/users/new/edit": "/users/:id/edit",
+		"/users/1/edit/x": "/users/*path",
+	}
+	for path, pattern := range cases {
+		n, _ := r.getRoute("GET", path)
+		if n == nil || n.pattern != pattern {
+			t.Fatalf("%s should match %s", path, pattern)
+		}
+	}
+	if n, _ := r.getRoute("GET", "/users/"); n != nil {
+		t.Fatalf("/users/ shouldn't match %s", n.pattern)
+	}
+}
+
+func TestRouteConflict(t *testing.T) {
+	cases := [][]string{
+		{"/p/:lang", "/p/:name"},
+		{"/p/:lang/doc", "/p/:lang/doc"},
+		{"/src/*filepath", "/src/*path"},
+		{"/src/*filepath/x"},
+		{"/p/a:b"},
+		{"/p/:a:b"},
+		{"/p/:"},
+		{"p/b"},
+	}
+	for _, patterns := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%v should panic", patterns)
+				}
+			}()
+			r := newRouter()
+			for _, pattern := range patterns {
+				r.addRoute("GET", pattern, nil)
+			}
+		}()
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := New()
+	r.GET("/user/:id", func(c *Context) { c.String(http.StatusOK, "get %s", c.Param("id")) })
+	r.DELETE("/user/:id", func(c *Context) { c.Status(http.StatusNoContent) })
+
+	cases := []struct {
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		{http.MethodGet, "/user/1", http.StatusOK, ""},
+		{http.MethodHead, "/user/1", http.StatusOK, ""},
+		{http.MethodPost, "/user/1", http.StatusMethodNotAllowed, "DELETE, GET, HEAD, OPTIONS"},
+		{http.MethodOptions, "/user/1", http.StatusNoContent, "DELETE, GET, HEAD, OPTIONS"},
+		{http.MethodPost, "/book/1", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.code {
+			t.Fatalf("%s %s: expect status %d, got %d", tc.method, tc.path, tc.code, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != tc.allow {
+			t.Fatalf("%s %s: expect Allow %q, got %q", tc.method, tc.path, tc.allow, allow)
+		}
+	}
+}
+
+func TestAutoOptionsMiddlewareOrder(t *testing.T) {
+	r := New()
+	var trace []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+	a := r.Group("")
+	a.Use(mark("a"))
+	a.POST("/items", func(c *Context) {})
+	b := r.Group("")
+	b.Use(mark("b"))
+	b.PUT("/items", func(c *Context) {})
+
+	// 同一路径的方法来自不同路由组时, 固定执行方法名排序后第一个路由所在组的中间件
+	for i := 0; i < 50; i++ {
+		trace = nil
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/items", nil))
+		if w.Code != http.StatusNoContent || strings.Join(trace, ",") != "a" {
+			t.Fatalf("unexpected response %d, trace %v", w.Code, trace)
+		}
+	}
+}
+
+func TestGroupMiddleware(t *testing.T) {
+	r := New()
+	var trace []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+	v1 := r.Group("/v1")
+	v1.Use(mark("v1"))
+	v1.GET("/hello", mark("route"), func(c *Context) { c.Status(http.StatusOK) })
+	v10 := r.Group("/v10")
+	v10.GET("/hello", func(c *Context) { c.Status(http.StatusOK) })
+
+	cases := map[string][]string{
+		"/v1/hello":  {"v1", "route"},
+		"/v10/hello": nil,
+	}
+	for path, expect := range cases {
+		trace = nil
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		if !reflect.DeepEqual(trace, expect) {
+			t.Fatalf("%s: expect middlewares %v, got %v", path, expect, trace)
+		}
+	}
 }
@@ -0,0 +1,148 @@
+package gee
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Route 一条已注册的路由, 可以通过 Name 命名后用 Engine.URL 反向生成路径
+type Route struct {
+	Method  string
+	Pattern string
+	Host    string // 通过 Engine.Host 注册时的 Host pattern
+	name    string
+	node    *node
+	engine  *Engine
+	doc     routeDoc // OpenAPI 文档标注
+}
+
+// RouteInfo 路由的描述信息, 用于生成接口清单
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Host        string   `json:"host,omitempty"`
+	Path        string   `json:"path"`
+	Name        string   `json:"name,omitempty"`
+	Handler     string   `json:"handler"`
+	Middlewares []string `json:"middlewares,omitempty"`
+}
+
+// Name 为路由命名, 名称在 Engine 中必须唯一
+func (r *Route) Name(name string) *Route {
+	engine := r.engine
+	if engine.namedRoutes == nil {
+		engine.namedRoutes = make(map[string]*Route)
+	}
+	if old, ok := engine.namedRoutes[name]; ok {
+		panic(fmt.Sprintf("gee: route name '%s' is already used by %s %s", name, old.Method, old.Pattern))
+	}
+	r.name = name
+	engine.namedRoutes[name] = r
+	return r
+}
+
+// URL 用 params 填充路由中的参数, 未出现在路由中的参数作为 query 追加
+func (r *Route) URL(params map[string]interface{}) (string, error) {
+	used := make(map[string]bool, len(params))
+	segments := strings.Split(r.Pattern, "/")
+	for i, seg := range segments {
+		spec, ok, _ := parseSegment(seg)
+		if !ok {
+			continue
+		}
+		key := spec.key
+		v, ok := params[key]
+		value := fmt.Sprint(v)
+		if !ok || value == "" {
+			return "", fmt.Errorf("gee: missing parameter '%s' for route %s", key, r.Pattern)
+		}
+		used[key] = true
+		if spec.kind == param {
+			if match := spec.matcher(); match != nil && !match(value) {
+				return "", fmt.Errorf("gee: parameter '%s' of route %s must match %s", key, r.Pattern, spec.constraint())
+			}
+			segments[i] = url.PathEscape(value)
+			continue
+		}
+		// 通配参数可以包含多级路径, 逐级转义
+		parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+		for j, part := range parts {
+			parts[j] = url.PathEscape(part)
+		}
+		segments[i] = strings.Join(parts, "/")
+	}
+	path := strings.Join(segments, "/")
+
+	query := url.Values{}
+	for k, v := range params {
+		if !used[k] {
+			query.Set(k, fmt.Sprint(v))
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
+
+// URL 根据路由名称生成路径, 例如 engine.URL("user.show", map[string]interface{}{"id": 1})
+func (engine *Engine) URL(name string, params map[string]interface{}) (string, error) {
+	route, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("gee: route '%s' is not defined", name)
+	}
+	return route.URL(params)
+}
+
+// Routes 按注册顺序返回所有路由
+func (engine *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(engine.router.routes))
+	for _, r := range engine.router.routes {
+		handlers := r.node.handlers
+		info := RouteInfo{
+			Method:  r.Method,
+			Host:    r.Host,
+			Path:    r.Pattern,
+			Name:    r.name,
+			Handler: nameOfFunction(handlers[len(handlers)-1]),
+		}
+		for _, h := range handlers[:len(handlers)-1] {
+			info.Middlewares = append(info.Middlewares, nameOfFunction(h))
+		}
+		routes = append(routes, info)
+	}
+	return routes
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// 模板中可用的内置函数, 用户通过 SetFuncMap 设置的同名函数优先:
+//
+//	<a href="{{url "user.show" "id" .ID}}">
+func (engine *Engine) templateFuncs() template.FuncMap {
+	funcs := template.FuncMap{
+		"url": func(name string, pairs ...interface{}) (string, error) {
+			if len(pairs)%2 != 0 {
+				return "", fmt.Errorf("gee: url %s: parameters must be key value pairs", name)
+			}
+			params := make(map[string]interface{}, len(pairs)/2)
+			for i := 0; i < len(pairs); i += 2 {
+				key, ok := pairs[i].(string)
+				if !ok {
+					return "", fmt.Errorf("gee: url %s: parameter name must be a string, got %T", name, pairs[i])
+				}
+				params[key] = pairs[i+1]
+			}
+			return engine.URL(name, params)
+		},
+	}
+	for k, v := range engine.funcMap {
+		funcs[k] = v
+	}
+	return funcs
+}
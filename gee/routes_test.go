@@ -0,0 +1,116 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func showUser(c *Context) {}
+
+func TestRouteURL(t *testing.T) {
+	r := New()
+	r.GET("/users/:id", showUser).Name("user.show")
+	r.GET("/files/*filepath", showUser).Name("file")
+	r.GET("/", showUser).Name("home")
+
+	cases := []struct {
+		name   string
+		params map[string]interface{}
+		want   string
+	}{
+		{"user.show", map[string]interface{}{"id": 42}, "/users/42"},
+		{"user.show", map[string]interface{}{"id": "a b/c", "tab": "posts"}, "/users/a%20b%2Fc?tab=posts"},
+		{"file", map[string]interface{}{"filepath": "/css/app main.css"}, "/files/css/app%20main.css"},
+		{"home", nil, "/"},
+	}
+	for _, tc := range cases {
+		got, err := r.URL(tc.name, tc.params)
+		if err != nil || got != tc.want {
+			t.Fatalf("URL(%s, %v) = %q, %v; want %q", tc.name, tc.params, got, err, tc.want)
+		}
+	}
+	if _, err := r.URL("user.show", nil); err == nil {
+		t.Fatal("missing parameter should fail")
+	}
+	if _, err := r.URL("unknown", nil); err == nil {
+		t.Fatal("unknown route should fail")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate route name should panic")
+		}
+	}()
+	r.POST("/users/:id", showUser).Name("user.show")
+}
+
+func TestAnyRoutes(t *testing.T) {
+	r := New()
+	routes := r.Any("/webhook/:id", showUser)
+	if len(routes) != len(anyMethods) || routes[0].Method != http.MethodGet || routes[0].Pattern != "/webhook/:id" {
+		t.Fatalf("unexpected routes %v", routes)
+	}
+	routes[0].Name("webhook")
+	for _, route := range routes {
+		route.Summary("Receive webhook")
+	}
+	mounted := r.Group("/admin").Mount("/legacy", http.NotFoundHandler())
+	mounted[len(mounted)-1].Name("legacy")
+
+	if got, err := r.URL("webhook", map[string]interface{}{"id": 7}); err != nil || got != "/webhook/7" {
+		t.Fatalf("unexpected url %q %v", got, err)
+	}
+	if got, err := r.URL("legacy", map[string]interface{}{"mountpath": "a/b"}); err != nil || got != "/admin/legacy/a/b" {
+		t.Fatalf("unexpected url %q %v", got, err)
+	}
+	if op := r.OpenAPI(OpenAPIConfig{}).Paths["/webhook/{id}"]["post"]; op == nil || op.Summary != "Receive webhook" {
+		t.Fatalf("unexpected operation %+v", op)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.Use(Recovery())
+	v1 := r.Group("/v1")
+	v1.Use(BodyLimit(1 << 20))
+	v1.GET("/users/:id", showUser).Name("user.show")
+	r.POST("/login", showUser)
+
+	routes := r.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+	got := routes[0]
+	if got.Method != "GET" || got.Path != "/v1/users/:id" || got.Name != "user.show" ||
+		!strings.HasSuffix(got.Handler, "gee.showUser") || len(got.Middlewares) != 2 {
+		t.Fatalf("unexpected route %+v", got)
+	}
+	if !strings.Contains(got.Middlewares[0], "RecoveryWithConfig") || !strings.Contains(got.Middlewares[1], "BodyLimit") {
+		t.Fatalf("unexpected middlewares %v", got.Middlewares)
+	}
+	if routes[1].Method != "POST" || routes[1].Path != "/login" || routes[1].Name != "" {
+		t.Fatalf("unexpected route %+v", routes[1])
+	}
+}
+
+func TestURLTemplateFunc(t *testing.T) {
+	r := New()
+	err := r.LoadHTMLFS(fstest.MapFS{
+		"index.tmpl": {Data: []byte(`<a href="{{url "user.show" "id" .id "tab" "posts"}}">`)},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/users/:id", showUser).Name("user.show")
+	r.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "index.tmpl", H{"id": 7})
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Body.String() != `<a href="/users/7?tab=posts">` {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+}
@@ -0,0 +1,78 @@
+package gee
+
+import (
+	"fmt"
+	"time"
+)
+
+// SecureConfig 安全相关的响应头配置, 字符串为空时不设置对应的响应头
+type SecureConfig struct {
+	HSTSMaxAge            time.Duration // Strict-Transport-Security 的 max-age, 仅对 HTTPS 请求设置
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	ContentSecurityPolicy string // Content-Security-Policy
+	FrameOptions          string // X-Frame-Options, 例如 DENY、SAMEORIGIN
+	ContentTypeNosniff    bool   // X-Content-Type-Options: nosniff
+	ReferrerPolicy        string // Referrer-Policy
+	PermissionsPolicy     string // Permissions-Policy
+	CrossOriginOpener     string // Cross-Origin-Opener-Policy
+}
+
+// DefaultSecureConfig 适合大多数站点的默认配置
+func DefaultSecureConfig() SecureConfig {
+	return SecureConfig{
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'self'",
+		FrameOptions:          "DENY",
+		ContentTypeNosniff:    true,
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		CrossOriginOpener:     "same-origin",
+	}
+}
+
+// Secure 在响应中添加安全相关的响应头
+func Secure(conf SecureConfig) HandlerFunc {
+	hsts := ""
+	if conf.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int64(conf.HSTSMaxAge/time.Second))
+		if conf.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if conf.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	headers := map[string]string{
+		"Content-Security-Policy":    conf.ContentSecurityPolicy,
+		"X-Frame-Options":            conf.FrameOptions,
+		"Referrer-Policy":            conf.ReferrerPolicy,
+		"Permissions-Policy":         conf.PermissionsPolicy,
+		"Cross-Origin-Opener-Policy": conf.CrossOriginOpener,
+	}
+	if conf.ContentTypeNosniff {
+		headers["X-Content-Type-Options"] = "nosniff"
+	}
+
+	return func(c *Context) {
+		h := c.W.Header()
+		for k, v := range headers {
+			if v != "" {
+				h.Set(k, v)
+			}
+		}
+		// HSTS 只能通过 HTTPS 下发, 否则会被浏览器忽略
+		if hsts != "" && c.IsHTTPS() {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
+
+// IsHTTPS 判断请求是否通过 HTTPS 到达, 来自可信代理时参考 X-Forwarded-Proto
+func (c *Context) IsHTTPS() bool {
+	if c.Req.TLS != nil {
+		return true
+	}
+	return c.fromTrustedProxy() && c.Req.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,40 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	r := New()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	r.Use(Secure(DefaultSecureConfig()))
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("X-Frame-Options") != "DENY" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	if w.Header().Get("Strict-Transport-Security") != "" {
+		t.Fatal("HSTS shouldn't be sent over plain HTTP")
+	}
+
+	// 只信任可信代理设置的 X-Forwarded-Proto
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Strict-Transport-Security") != "" {
+		t.Fatal("X-Forwarded-Proto from untrusted peer should be ignored")
+	}
+	req.RemoteAddr = "10.0.0.1:1234"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Strict-Transport-Security") != "max-age=31536000; includeSubDomains" {
+		t.Fatalf("HSTS = %q", w.Header().Get("Strict-Transport-Security"))
+	}
+}
@@ -0,0 +1,162 @@
+package gee
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// 按 Engine 的配置创建 http.Server 并记录下来, 供 Shutdown 使用;
+// 已经调用过 Shutdown 时返回 http.ErrServerClosed
+func (engine *Engine) newServer(addr string) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           engine,
+		ReadTimeout:       engine.ReadTimeout,
+		ReadHeaderTimeout: engine.ReadHeaderTimeout,
+		WriteTimeout:      engine.WriteTimeout,
+		IdleTimeout:       engine.IdleTimeout,
+		MaxHeaderBytes:    engine.MaxHeaderBytes,
+	}
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	if engine.shuttingDown {
+		return nil, http.ErrServerClosed
+	}
+	for _, f := range engine.onShutdown {
+		srv.RegisterOnShutdown(f)
+	}
+	engine.servers = append(engine.servers, srv)
+	return srv, nil
+}
+
+// 调用方需持有 engine.mu
+func (engine *Engine) shutdownDoneLocked() chan struct{} {
+	if engine.shutdownDone == nil {
+		engine.shutdownDone = make(chan struct{})
+	}
+	return engine.shutdownDone
+}
+
+// Shutdown 开始时 Serve 系列方法立即返回 http.ErrServerClosed,
+// 此时等 Shutdown 处理完进行中的请求再返回 nil, 避免 main 提前退出
+func (engine *Engine) serve(serve func() error) error {
+	err := serve()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	engine.mu.Lock()
+	done := engine.shutdownDoneLocked()
+	engine.mu.Unlock()
+	<-done
+	return nil
+}
+
+func (engine *Engine) Run(addr string) (err error) {
+	srv, err := engine.newServer(addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Listening and serving HTTP on %s", addr)
+	return engine.serve(srv.ListenAndServe)
+}
+
+// RunTLS 以 HTTPS 方式监听 addr
+func (engine *Engine) RunTLS(addr string, certFile string, keyFile string) (err error) {
+	srv, err := engine.newServer(addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Listening and serving HTTPS on %s", addr)
+	return engine.serve(func() error { return srv.ListenAndServeTLS(certFile, keyFile) })
+}
+
+// RunUnix 监听 unix socket, file 已存在时会先删除
+func (engine *Engine) RunUnix(file string) (err error) {
+	log.Printf("Listening and serving HTTP on unix:/%s", file)
+	if err = os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	listener, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	return engine.RunListener(listener)
+}
+
+// RunListener 在已有的 listener 上提供服务
+func (engine *Engine) RunListener(listener net.Listener) (err error) {
+	srv, err := engine.newServer(listener.Addr().String())
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	return engine.serve(func() error { return srv.Serve(listener) })
+}
+
+// OnShutdown 注册 Shutdown 时调用的函数, 例如关闭被劫持的长连接
+func (engine *Engine) OnShutdown(f func()) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.onShutdown = append(engine.onShutdown, f)
+	for _, srv := range engine.servers {
+		srv.RegisterOnShutdown(f)
+	}
+}
+
+// Shutdown 优雅关闭: 立即停止接收新连接, 等待处理中的请求完成或 ctx 超时,
+// 之后 Run 系列方法才返回; Shutdown 之后再调用 Run 会返回 http.ErrServerClosed
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.mu.Lock()
+	done := engine.shutdownDoneLocked()
+	if engine.shuttingDown {
+		// 已有 Shutdown 在进行, 等待它结束
+		engine.mu.Unlock()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	engine.shuttingDown = true
+	servers := engine.servers
+	engine.servers = nil
+	engine.mu.Unlock()
+
+	var err error
+	for _, srv := range servers {
+		if e := srv.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	close(done)
+	return err
+}
+
+// ShutdownOnSignal 收到 sigs 中的信号(默认 SIGINT、SIGTERM)后调用 Shutdown,
+// 最多等待 timeout, 需在 Run 之前调用
+func (engine *Engine) ShutdownOnSignal(timeout time.Duration, sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, sigs...)
+	go func() {
+		sig := <-quit
+		signal.Stop(quit)
+		log.Printf("Received %s, shutting down server ...", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := engine.Shutdown(ctx); err != nil {
+			log.Println("Server shutdown:", err)
+		}
+	}()
+}
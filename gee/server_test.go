@@ -0,0 +1,109 @@
+package gee
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	r := New()
+	started := make(chan struct{})
+	r.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "http://" + listener.Addr().String()
+	served := make(chan error, 1)
+	go func() { served <- r.RunListener(listener) }()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(addr + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if b := <-body; b != "done" {
+		t.Fatalf("in-flight request should complete, got %q", b)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("RunListener should return nil after Shutdown, got %v", err)
+	}
+	if _, err := http.Get(addr + "/slow"); err == nil {
+		t.Fatal("new requests should be rejected after Shutdown")
+	}
+}
+
+func TestRunWaitsForShutdown(t *testing.T) {
+	r := New()
+	started := make(chan struct{})
+	var finished int32
+	r.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		c.String(http.StatusOK, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- r.RunListener(listener) }()
+	go func() {
+		if resp, err := http.Get("http://" + listener.Addr().String() + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	go r.Shutdown(context.Background())
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+	// Run 返回时处理中的请求必须已经结束
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Fatal("RunListener returned before the in-flight handler finished")
+	}
+}
+
+func TestRunAfterShutdown(t *testing.T) {
+	r := New()
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RunListener(listener); !errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("expect http.ErrServerClosed, got %v", err)
+	}
+	if err := r.Run("127.0.0.1:0"); !errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("expect http.ErrServerClosed, got %v", err)
+	}
+}
@@ -0,0 +1,462 @@
+package gee
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SessionKey 会话在 Context 中的 key
+const SessionKey = "gee.session"
+
+// SessionStore 服务端会话存储, cookie 中只保存会话 ID
+type SessionStore interface {
+	Get(id string) ([]byte, error) // 会话不存在或已过期时返回 nil, nil
+	Set(id string, data []byte, ttl time.Duration) error
+	Delete(id string) error
+}
+
+// SessionConfig 会话配置
+type SessionConfig struct {
+	// Store 为 nil 时会话数据加密后整体保存在 cookie 中, 此时必须设置 Secret
+	Store           SessionStore
+	Secret          []byte        // cookie 存储的密钥, 用于派生 AES-GCM 密钥, 同时保证数据不可读、不可篡改
+	CookieName      string        // 默认 gee_session
+	Path            string        // 默认 /
+	Domain          string        //
+	Secure          bool          //
+	SameSite        http.SameSite // 默认 SameSiteLaxMode
+	IdleTimeout     time.Duration // 空闲超过该时间后失效, 默认 30 分钟; 每次请求都会续期
+	AbsoluteTimeout time.Duration // 自创建起超过该时间后失效, 即使一直在使用, 默认 24 小时
+}
+
+var (
+	ErrSessionHeadersSent = errors.New("gee: session cannot be saved after response headers were sent")
+	ErrSessionTooLarge    = errors.New("gee: session data exceeds cookie size limit")
+	ErrSessionStore       = errors.New("gee: session store unavailable")
+)
+
+// 浏览器对单个 cookie 的大小限制
+const maxCookieSize = 4096
+
+// 会话的序列化格式, 自定义类型的值需要先调用 gob.Register 注册
+type sessionData struct {
+	ID       string
+	Values   map[string]interface{}
+	Created  time.Time
+	Accessed time.Time
+}
+
+func init() {
+	gob.Register([]interface{}{})
+}
+
+// Session 当前请求的会话, 由 Sessions 中间件创建, 修改在响应头发送前自动保存
+type Session struct {
+	data      sessionData
+	conf      *SessionConfig
+	aead      cipher.AEAD
+	c         *Context
+	mu        sync.Mutex
+	isNew     bool
+	modified  bool
+	rotate    bool
+	destroyed bool
+	saved     bool
+	expired   bool  // 请求携带了已失效的会话 cookie
+	loadErr   error // 读取存储失败, 本次请求不修改会话 cookie
+}
+
+// Sessions 会话中间件, 在 handler 中通过 c.Session() 获取会话
+func Sessions(conf SessionConfig) HandlerFunc {
+	if conf.CookieName == "" {
+		conf.CookieName = "gee_session"
+	}
+	if conf.Path == "" {
+		conf.Path = "/"
+	}
+	if conf.SameSite == 0 {
+		conf.SameSite = http.SameSiteLaxMode
+	}
+	if conf.IdleTimeout == 0 {
+		conf.IdleTimeout = 30 * time.Minute
+	}
+	if conf.AbsoluteTimeout == 0 {
+		conf.AbsoluteTimeout = 24 * time.Hour
+	}
+	var aead cipher.AEAD
+	if conf.Store == nil {
+		if len(conf.Secret) == 0 {
+			panic("gee: SessionConfig.Secret is required for cookie sessions")
+		}
+		key := sha256.Sum256(append([]byte("gee-session:"), conf.Secret...))
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			panic(err)
+		}
+		if aead, err = cipher.NewGCM(block); err != nil {
+			panic(err)
+		}
+	}
+
+	return func(c *Context) {
+		s := &Session{conf: &conf, aead: aead, c: c}
+		s.load(time.Now())
+		c.Set(SessionKey, s)
+		c.W.Before(func() {
+			if err := s.save(time.Now()); err != nil {
+				log.Printf("[GEE] failed to save session: %v", err)
+			}
+		})
+		c.Next()
+	}
+}
+
+// Session 返回当前请求的会话, 未使用 Sessions 中间件时返回 nil
+func (c *Context) Session() *Session {
+	if s, ok := c.Get(SessionKey); ok {
+		return s.(*Session)
+	}
+	return nil
+}
+
+// 从 cookie 恢复会话, 无效或过期时创建新会话;
+// 存储出错时不能判断会话是否有效, 保留 cookie, 避免存储短暂故障时所有用户被登出
+func (s *Session) load(now time.Time) {
+	s.isNew = true
+	s.data = sessionData{Values: make(map[string]interface{}), Created: now, Accessed: now}
+	cookie, err := s.c.Req.Cookie(s.conf.CookieName)
+	if err != nil || cookie.Value == "" {
+		return
+	}
+	data, err := s.decode(cookie.Value)
+	if errors.Is(err, ErrSessionStore) {
+		s.loadErr = err
+		return
+	}
+	if err != nil || data == nil {
+		s.expired = true
+		return
+	}
+	if s.conf.expired(data, now) {
+		s.expired = true
+		if s.conf.Store != nil {
+			_ = s.conf.Store.Delete(data.ID)
+		}
+		return
+	}
+	if data.Values == nil {
+		data.Values = make(map[string]interface{})
+	}
+	s.data = *data
+	s.isNew = false
+}
+
+func (conf *SessionConfig) expired(data *sessionData, now time.Time) bool {
+	return now.Sub(data.Accessed) > conf.IdleTimeout || now.Sub(data.Created) > conf.AbsoluteTimeout
+}
+
+// 服务端存储时 cookie 的值为会话 ID, 否则为加密后的会话数据
+func (s *Session) decode(value string) (*sessionData, error) {
+	var raw []byte
+	if s.conf.Store != nil {
+		var err error
+		if raw, err = s.conf.Store.Get(value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSessionStore, err)
+		}
+		if raw == nil {
+			return nil, nil
+		}
+	} else {
+		sealed, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		size := s.aead.NonceSize()
+		if len(sealed) < size {
+			return nil, errors.New("gee: malformed session cookie")
+		}
+		// cookie 名作为附加数据, 防止把其他 cookie 的密文替换进来
+		if raw, err = s.aead.Open(nil, sealed[:size], sealed[size:], []byte(s.conf.CookieName)); err != nil {
+			return nil, err
+		}
+	}
+	data := new(sessionData)
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(data); err != nil {
+		return nil, err
+	}
+	if s.conf.Store != nil && data.ID != value {
+		return nil, errors.New("gee: session id mismatch")
+	}
+	return data, nil
+}
+
+// Save 立即保存会话, 通常不需要调用, 中间件会在响应头发送前自动保存;
+// 需要处理存储错误时可以在写响应之前显式调用
+func (s *Session) Save() error {
+	if s.c.W.Written() {
+		return ErrSessionHeadersSent
+	}
+	return s.save(time.Now())
+}
+
+func (s *Session) save(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loadErr != nil {
+		return s.loadErr
+	}
+	if s.destroyed {
+		if s.saved {
+			return nil
+		}
+		s.saved = true
+		s.setCookie("", -1)
+		if s.conf.Store != nil && s.data.ID != "" {
+			return s.conf.Store.Delete(s.data.ID)
+		}
+		return nil
+	}
+	// 没有修改的新会话不下发 cookie, 避免为每个匿名访问者创建会话;
+	// 已有会话在每个请求中保存一次以刷新空闲时间
+	if !s.modified && (s.isNew || s.saved) {
+		if s.expired && !s.saved {
+			s.saved = true
+			s.setCookie("", -1)
+		}
+		return nil
+	}
+
+	if s.rotate || s.data.ID == "" {
+		if s.conf.Store != nil && s.data.ID != "" && !s.isNew {
+			if err := s.conf.Store.Delete(s.data.ID); err != nil {
+				return err
+			}
+		}
+		s.data.ID = newSessionID()
+	}
+	s.data.Accessed = now
+	ttl := s.conf.IdleTimeout
+	if remaining := s.data.Created.Add(s.conf.AbsoluteTimeout).Sub(now); remaining < ttl {
+		ttl = remaining
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&s.data); err != nil {
+		return err
+	}
+	value := s.data.ID
+	if s.conf.Store != nil {
+		if err := s.conf.Store.Set(s.data.ID, buf.Bytes(), ttl); err != nil {
+			return err
+		}
+	} else {
+		nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+buf.Len()+s.aead.Overhead())
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		value = base64.RawURLEncoding.EncodeToString(s.aead.Seal(nonce, nonce, buf.Bytes(), []byte(s.conf.CookieName)))
+		if len(value)+len(s.conf.CookieName) > maxCookieSize {
+			return ErrSessionTooLarge
+		}
+	}
+	s.setCookie(value, int(ttl/time.Second))
+	s.isNew, s.modified, s.rotate, s.saved = false, false, false, true
+	return nil
+}
+
+func (s *Session) setCookie(value string, maxAge int) {
+	cookie := &http.Cookie{
+		Name:     s.conf.CookieName,
+		Value:    value,
+		Path:     s.conf.Path,
+		Domain:   s.conf.Domain,
+		MaxAge:   maxAge,
+		Secure:   s.conf.Secure,
+		HttpOnly: true,
+		SameSite: s.conf.SameSite,
+	}
+	// 同一个响应中多次保存时只保留最后一次的 cookie
+	h := s.c.W.Header()
+	prefix := s.conf.CookieName + "="
+	cookies := h.Values("Set-Cookie")
+	kept := cookies[:0:0]
+	for _, v := range cookies {
+		if len(v) < len(prefix) || v[:len(prefix)] != prefix {
+			kept = append(kept, v)
+		}
+	}
+	h["Set-Cookie"] = append(kept, cookie.String())
+}
+
+func newSessionID() string {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("gee: failed to generate session id: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// ID 返回会话 ID, 新会话在第一次保存前为空字符串
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.ID
+}
+
+// IsNew 会话是否在本次请求中创建
+func (s *Session) IsNew() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isNew
+}
+
+func (s *Session) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.Values[key]
+}
+
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Values[key] = value
+	s.modified = true
+}
+
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data.Values, key)
+	s.modified = true
+}
+
+// Clear 清空会话中的所有数据, 会话本身仍然有效
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Values = make(map[string]interface{})
+	s.modified = true
+}
+
+// Rotate 保留数据并更换会话 ID, 登录等权限变化后调用以防止会话固定攻击
+func (s *Session) Rotate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotate = true
+	s.modified = true
+}
+
+// Destroy 删除会话并让浏览器删除 cookie, 例如退出登录
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Values = make(map[string]interface{})
+	s.destroyed = true
+	s.saved = false
+}
+
+func flashKey(key []string) string {
+	if len(key) > 0 {
+		return "_flash." + key[0]
+	}
+	return "_flash"
+}
+
+// AddFlash 添加一条闪现消息, 在下一次调用 Flashes 时读取并删除, 可选参数为消息分类
+func (s *Session) AddFlash(value interface{}, key ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := flashKey(key)
+	flashes, _ := s.data.Values[k].([]interface{})
+	s.data.Values[k] = append(flashes, value)
+	s.modified = true
+}
+
+// Flashes 读取并删除闪现消息
+func (s *Session) Flashes(key ...string) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := flashKey(key)
+	flashes, ok := s.data.Values[k].([]interface{})
+	if !ok {
+		return nil
+	}
+	delete(s.data.Values, k)
+	s.modified = true
+	return flashes
+}
+
+// MemoryStore 进程内的会话存储, 适合单机部署和测试
+type MemoryStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+}
+
+type memorySession struct {
+	data   []byte
+	expiry time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memorySession)}
+}
+
+func (m *MemoryStore) Get(id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(s.expiry) {
+		delete(m.sessions, id)
+		return nil, nil
+	}
+	return s.data, nil
+}
+
+// Set 保存会话, 每分钟最多清理一次过期的会话
+func (m *MemoryStore) Set(id string, data []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("gee: invalid session ttl %v", ttl)
+	}
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.lastSweep) > time.Minute {
+		for k, s := range m.sessions {
+			if now.After(s.expiry) {
+				delete(m.sessions, k)
+			}
+		}
+		m.lastSweep = now
+	}
+	m.sessions[id] = memorySession{data: append([]byte(nil), data...), expiry: now.Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// Len 返回保存的会话数量, 包括尚未清理的过期会话
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions)
+}
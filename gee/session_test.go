@@ -0,0 +1,213 @@
+package gee
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 保存响应中的 cookie 并在之后的请求中携带, 模拟浏览器
+type cookieJar map[string]*http.Cookie
+
+func (jar cookieJar) do(r *Engine, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for _, c := range jar {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	for _, c := range w.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(jar, c.Name)
+		} else {
+			jar[c.Name] = c
+		}
+	}
+	return w
+}
+
+func newSessionEngine(conf SessionConfig) *Engine {
+	r := New()
+	r.Use(Sessions(conf))
+	r.GET("/login", func(c *Context) {
+		s := c.Session()
+		s.Set("user", "tiam")
+		s.Rotate()
+		s.AddFlash("welcome")
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/me", func(c *Context) {
+		s := c.Session()
+		user, _ := s.Get("user").(string)
+		var flashes []string
+		for _, f := range s.Flashes() {
+			flashes = append(flashes, f.(string))
+		}
+		c.String(http.StatusOK, "%s|%s|%s", user, strings.Join(flashes, ","), s.ID())
+	})
+	r.GET("/logout", func(c *Context) {
+		c.Session().Destroy()
+		c.String(http.StatusOK, "bye")
+	})
+	return r
+}
+
+func testSessionFlow(t *testing.T, r *Engine) {
+	jar := cookieJar{}
+	if w := jar.do(r, "GET", "/me"); w.Body.String() != "||" || len(jar) != 0 {
+		t.Fatalf("anonymous request shouldn't create a session: %q %v", w.Body.String(), jar)
+	}
+	jar.do(r, "GET", "/login")
+	first := jar["gee_session"]
+	if first == nil || !first.HttpOnly {
+		t.Fatalf("session cookie = %v", first)
+	}
+	w := jar.do(r, "GET", "/me")
+	parts := strings.Split(w.Body.String(), "|")
+	if parts[0] != "tiam" || parts[1] != "welcome" || parts[2] == "" {
+		t.Fatalf("unexpected session %q", w.Body.String())
+	}
+	// 闪现消息只能读取一次, 会话 ID 在请求之间保持不变
+	w = jar.do(r, "GET", "/me")
+	if w.Body.String() != "tiam||"+parts[2] {
+		t.Fatalf("unexpected session %q", w.Body.String())
+	}
+	// Rotate 更换会话 ID
+	jar.do(r, "GET", "/login")
+	w = jar.do(r, "GET", "/me")
+	if strings.HasSuffix(w.Body.String(), parts[2]) {
+		t.Fatal("session id should be rotated")
+	}
+
+	jar.do(r, "GET", "/logout")
+	if len(jar) != 0 {
+		t.Fatal("session cookie should be deleted")
+	}
+}
+
+func TestCookieSession(t *testing.T) {
+	r := newSessionEngine(SessionConfig{Secret: []byte("secret")})
+	testSessionFlow(t, r)
+
+	// 篡改的 cookie 被视为无效
+	jar := cookieJar{}
+	jar.do(r, "GET", "/login")
+	c := jar["gee_session"]
+	c.Value = c.Value[:len(c.Value)-2] + "AA"
+	if w := jar.do(r, "GET", "/me"); strings.HasPrefix(w.Body.String(), "tiam") {
+		t.Fatal("tampered cookie should be rejected")
+	}
+}
+
+func TestServerSession(t *testing.T) {
+	store := NewMemoryStore()
+	r := newSessionEngine(SessionConfig{Store: store})
+	testSessionFlow(t, r)
+	if store.Len() != 0 {
+		t.Fatalf("rotated and destroyed sessions should be deleted, store len = %d", store.Len())
+	}
+
+	jar := cookieJar{}
+	jar.do(r, "GET", "/login")
+	old := *jar["gee_session"]
+	jar.do(r, "GET", "/logout")
+	jar["gee_session"] = &old
+	if w := jar.do(r, "GET", "/me"); strings.HasPrefix(w.Body.String(), "tiam") {
+		t.Fatal("destroyed session shouldn't be usable")
+	}
+}
+
+// 可以模拟故障的存储
+type flakyStore struct {
+	*MemoryStore
+	down bool
+}
+
+func (s *flakyStore) Get(id string) ([]byte, error) {
+	if s.down {
+		return nil, errors.New("connection refused")
+	}
+	return s.MemoryStore.Get(id)
+}
+
+func TestSessionStoreError(t *testing.T) {
+	store := &flakyStore{MemoryStore: NewMemoryStore()}
+	r := newSessionEngine(SessionConfig{Store: store})
+	var saveErr error
+	r.GET("/save", func(c *Context) {
+		c.Session().Set("k", "v")
+		saveErr = c.Session().Save()
+	})
+	jar := cookieJar{}
+	jar.do(r, "GET", "/login")
+	cookie := jar["gee_session"]
+
+	// 存储故障时不删除也不替换会话 cookie
+	store.down = true
+	for _, path := range []string{"/me", "/save", "/logout"} {
+		if w := jar.do(r, "GET", path); len(w.Result().Cookies()) != 0 || jar["gee_session"] != cookie {
+			t.Fatalf("%s: session cookie shouldn't change, got %v", path, w.Result().Cookies())
+		}
+	}
+	if !errors.Is(saveErr, ErrSessionStore) {
+		t.Fatalf("Save should report the store error, got %v", saveErr)
+	}
+
+	store.down = false
+	if w := jar.do(r, "GET", "/me"); !strings.HasPrefix(w.Body.String(), "tiam") {
+		t.Fatalf("session should survive the outage, got %q", w.Body.String())
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	r := newSessionEngine(SessionConfig{Store: NewMemoryStore(), IdleTimeout: 100 * time.Millisecond, AbsoluteTimeout: 300 * time.Millisecond})
+	loggedIn := func(jar cookieJar) bool {
+		return strings.HasPrefix(jar.do(r, "GET", "/me").Body.String(), "tiam")
+	}
+
+	jar := cookieJar{}
+	jar.do(r, "GET", "/login")
+	// 每次请求都会续期空闲时间, 但不能超过绝对过期时间
+	for i := 0; i < 4; i++ {
+		time.Sleep(60 * time.Millisecond)
+		if !loggedIn(jar) {
+			t.Fatalf("session should be refreshed on request %d", i)
+		}
+	}
+	time.Sleep(60 * time.Millisecond)
+	if loggedIn(jar) {
+		t.Fatal("session should expire after absolute timeout")
+	}
+	if len(jar) != 0 {
+		t.Fatal("expired session cookie should be deleted")
+	}
+
+	jar.do(r, "GET", "/login")
+	time.Sleep(150 * time.Millisecond)
+	if loggedIn(jar) {
+		t.Fatal("session should expire after idle timeout")
+	}
+}
+
+func TestCookieHelpers(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		v, err := c.Cookie("name")
+		if err != nil {
+			t.Error(err)
+		}
+		c.SetSameSite(http.SameSiteStrictMode)
+		c.SetCookie("echo", v+"!", 60, "", "", true, true)
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "name", Value: "a%20b"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cookie := w.Result().Cookies()[0]
+	if cookie.Value != "a+b%21" || cookie.Path != "/" || cookie.SameSite != http.SameSiteStrictMode || !cookie.Secure {
+		t.Fatalf("unexpected cookie %+v", cookie)
+	}
+}
@@ -0,0 +1,248 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const MIMEEventStream = "text/event-stream"
+
+// SSEvent 一个 Server-Sent Event, Data 为 string 或 []byte 时原样发送, 其他类型编码为 JSON
+type SSEvent struct {
+	ID    string
+	Event string
+	Data  interface{}
+	Retry time.Duration // 通知浏览器断线后的重连间隔
+}
+
+// 按 text/event-stream 格式编码, 多行数据拆分为多个 data 字段
+func (e SSEvent) encode() ([]byte, error) {
+	var data string
+	switch d := e.Data.(type) {
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		data = string(b)
+	}
+	var buf bytes.Buffer
+	// id 和 event 中不能包含换行, 否则会破坏事件边界
+	if e.ID != "" {
+		buf.WriteString("id: " + stripNewlines(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		buf.WriteString("event: " + stripNewlines(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(int64(e.Retry/time.Millisecond), 10) + "\n")
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// 第一次发送事件前设置响应头, 关闭代理缓冲
+func (c *Context) sseHeaders() {
+	if c.W.Written() {
+		return
+	}
+	h := c.W.Header()
+	h.Set("Content-Type", MIMEEventStream)
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+}
+
+// SSEvent 发送一个事件并立即刷新, 通常在 Stream 的回调中调用
+func (c *Context) SSEvent(name string, data interface{}) {
+	_ = c.SSE(SSEvent{Event: name, Data: data})
+}
+
+// SSE 发送一个完整的事件, 可以设置 id 和 retry
+func (c *Context) SSE(event SSEvent) error {
+	frame, err := event.encode()
+	if err != nil {
+		return err
+	}
+	c.sseHeaders()
+	if _, err = c.W.Write(frame); err != nil {
+		return err
+	}
+	c.W.Flush()
+	return nil
+}
+
+// 已编码的事件, seq 用于断线重连时的回放
+type sseMessage struct {
+	seq   uint64
+	frame []byte
+}
+
+// Broker 向多个 SSE 客户端广播事件, 保留最近的事件供 Last-Event-ID 断线续传
+type Broker struct {
+	Heartbeat  time.Duration // 心跳间隔, 防止代理断开空闲连接, 默认 15 秒
+	ClientSize int           // 每个客户端的发送队列长度, 队列满时断开该客户端, 默认 64
+
+	mu      sync.Mutex
+	seq     uint64
+	replay  []sseMessage
+	size    int
+	clients map[chan sseMessage]struct{}
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// NewBroker 创建 Broker, replaySize 为回放缓冲保留的事件数
+func NewBroker(replaySize int) *Broker {
+	return &Broker{
+		Heartbeat:  15 * time.Second,
+		ClientSize: 64,
+		size:       replaySize,
+		clients:    make(map[chan sseMessage]struct{}),
+		closed:     make(chan struct{}),
+	}
+}
+
+// Publish 广播事件, 事件 ID 由 Broker 按顺序分配
+func (b *Broker) Publish(event string, data interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	seq := b.seq + 1
+	frame, err := SSEvent{ID: strconv.FormatUint(seq, 10), Event: event, Data: data}.encode()
+	if err != nil {
+		return err
+	}
+	b.seq = seq
+	msg := sseMessage{seq: seq, frame: frame}
+	if b.size > 0 {
+		if len(b.replay) == b.size {
+			copy(b.replay, b.replay[1:])
+			b.replay = b.replay[:b.size-1]
+		}
+		b.replay = append(b.replay, msg)
+	}
+	for ch := range b.clients {
+		select {
+		case ch <- msg:
+		default:
+			// 客户端太慢, 断开后由浏览器带着 Last-Event-ID 重连并回放
+			delete(b.clients, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// 注册客户端并返回 lastID 之后仍在缓冲中的事件
+func (b *Broker) subscribe(lastID string) (chan sseMessage, []sseMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	size := b.ClientSize
+	if size <= 0 {
+		size = 64
+	}
+	ch := make(chan sseMessage, size)
+	b.clients[ch] = struct{}{}
+	if lastID == "" {
+		return ch, nil
+	}
+	last, err := strconv.ParseUint(lastID, 10, 64)
+	if err != nil {
+		return ch, nil
+	}
+	var missed []sseMessage
+	for _, msg := range b.replay {
+		if msg.seq > last {
+			missed = append(missed, msg)
+		}
+	}
+	return ch, missed
+}
+
+func (b *Broker) unsubscribe(ch chan sseMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.clients[ch]; ok {
+		delete(b.clients, ch)
+		close(ch)
+	}
+}
+
+// Len 当前客户端数量
+func (b *Broker) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
+
+// Serve 作为 HandlerFunc 使用, 例如 r.GET("/events", broker.Serve),
+// 直到客户端断开、被判定为慢客户端或 Broker 关闭时返回
+func (b *Broker) Serve(c *Context) {
+	lastID := c.Req.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("lastEventId")
+	}
+	ch, missed := b.subscribe(lastID)
+	defer b.unsubscribe(ch)
+
+	c.sseHeaders()
+	for _, msg := range missed {
+		if _, err := c.W.Write(msg.frame); err != nil {
+			return
+		}
+	}
+	c.W.Flush()
+
+	heartbeat := b.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		case <-b.closed:
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := c.W.Write(msg.frame); err != nil {
+				return
+			}
+			c.W.Flush()
+		case <-ticker.C:
+			// 以冒号开头的行是注释, 浏览器会忽略
+			if _, err := c.W.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+			c.W.Flush()
+		}
+	}
+}
+
+// Close 断开所有客户端, 可以注册到 Engine.OnShutdown
+func (b *Broker) Close() {
+	b.once.Do(func() { close(b.closed) })
+}
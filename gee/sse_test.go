@@ -0,0 +1,130 @@
+package gee
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEvent(t *testing.T) {
+	r := New()
+	r.GET("/stream", func(c *Context) {
+		i := 0
+		c.Stream(func(w io.Writer) bool {
+			c.SSEvent("message", "line1\nline2")
+			i++
+			return i < 2
+		})
+		_ = c.SSE(SSEvent{ID: "3", Event: "json", Data: H{"a": 1}, Retry: time.Second})
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil))
+	want := "event: message\ndata: line1\ndata: line2\n\n" +
+		"event: message\ndata: line1\ndata: line2\n\n" +
+		"id: 3\nevent: json\nretry: 1000\ndata: {\"a\":1}\n\n"
+	if w.Body.String() != want || w.Header().Get("Content-Type") != MIMEEventStream || !w.Flushed {
+		t.Fatalf("unexpected stream %q %v", w.Body.String(), w.Header())
+	}
+}
+
+// 读取下一个事件, 跳过心跳注释, 返回 id 和 data
+func readSSE(t *testing.T, br *bufio.Reader) (id, data string) {
+	t.Helper()
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && data != "":
+			return id, data
+		case strings.HasPrefix(line, "id: "):
+			id = line[4:]
+		case strings.HasPrefix(line, "data: "):
+			data = line[6:]
+		}
+	}
+}
+
+func TestBroker(t *testing.T) {
+	broker := NewBroker(10)
+	r := New()
+	r.GET("/events", broker.Serve)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	defer broker.Close()
+
+	connect := func(lastID string) (*http.Response, *bufio.Reader, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/events", nil)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, bufio.NewReader(resp.Body), cancel
+	}
+	waitClients := func(n int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for broker.Len() != n && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if broker.Len() != n {
+			t.Fatalf("broker clients = %d, want %d", broker.Len(), n)
+		}
+	}
+
+	resp, br, cancel := connect("")
+	waitClients(1)
+	for _, msg := range []string{"a", "b", "c"} {
+		_ = broker.Publish("message", msg)
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		if _, data := readSSE(t, br); data != want {
+			t.Fatalf("event %d = %q, want %q", i, data, want)
+		}
+	}
+	cancel()
+	resp.Body.Close()
+	waitClients(0)
+
+	// 断线期间发布的事件在重连时回放
+	_ = broker.Publish("message", "d")
+	_ = broker.Publish("message", "e")
+	resp, br, cancel = connect("3")
+	defer cancel()
+	defer resp.Body.Close()
+	for _, want := range []string{"4:d", "5:e"} {
+		if id, data := readSSE(t, br); id+":"+data != want {
+			t.Fatalf("replayed %s:%s, want %s", id, data, want)
+		}
+	}
+}
+
+func TestBrokerHeartbeat(t *testing.T) {
+	broker := NewBroker(0)
+	broker.Heartbeat = 10 * time.Millisecond
+	r := New()
+	r.GET("/events", broker.Serve)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != ": ping\n" {
+		t.Fatalf("heartbeat = %q, %v", line, err)
+	}
+	broker.Close()
+}
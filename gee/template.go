@@ -0,0 +1,187 @@
+package gee
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// HTMLRender 按模板名渲染 HTML, 可以通过 Engine.SetHTMLRender 替换
+type HTMLRender interface {
+	Render(w io.Writer, name string, data interface{}) error
+}
+
+// 模板加载函数, 开启 HTMLAutoReload 或修改 funcMap 时重新调用, funcMap 包含内置的 url 等函数
+type htmlLoader func(funcMap template.FuncMap) (HTMLRender, error)
+
+// 所有模板位于同一个集合中, 按名称执行, 对应 LoadHTMLGlob / LoadHTMLFS
+type htmlSet struct {
+	t *template.Template
+}
+
+func (r htmlSet) Render(w io.Writer, name string, data interface{}) error {
+	return r.t.ExecuteTemplate(w, name, data)
+}
+
+// TemplateConfig 带布局的模板配置, 每个页面与布局、公共片段组成独立的模板集合,
+// 因此不同页面可以用 {{define "content"}} 覆盖布局中同名的 {{block "content" .}}
+type TemplateConfig struct {
+	FS       fs.FS    // 模板所在的文件系统, 例如 embed.FS 或 os.DirFS("templates")
+	Layouts  []string // 布局模板的 glob, 例如 "layouts/*.tmpl"
+	Partials []string // 公共片段的 glob, 例如 "partials/*.tmpl"
+	Pages    []string // 页面模板的 glob, 例如 "pages/*.tmpl"
+	Layout   string   // 默认执行的布局模板名(相对 FS 的路径), 为空时直接执行页面模板
+}
+
+// 页面名 -> 模板集合
+type layoutRender struct {
+	pages  map[string]*template.Template
+	layout string
+}
+
+func (r *layoutRender) Render(w io.Writer, name string, data interface{}) error {
+	t, ok := r.pages[name]
+	if !ok {
+		return fmt.Errorf("gee: html template %q is not loaded", name)
+	}
+	if r.layout != "" && t.Lookup(r.layout) != nil {
+		return t.ExecuteTemplate(w, r.layout, data)
+	}
+	return t.ExecuteTemplate(w, name, data)
+}
+
+// 展开 glob 并去重, 结果按路径排序保证加载顺序稳定
+func globFS(fsys fs.FS, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// 以文件相对路径作为模板名解析, 避免不同目录下同名文件互相覆盖
+func parseFiles(t *template.Template, fsys fs.FS, files []string) error {
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		if _, err = t.New(file).Parse(string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadLayouts(conf TemplateConfig, funcMap template.FuncMap) (HTMLRender, error) {
+	if conf.FS == nil {
+		return nil, fmt.Errorf("gee: TemplateConfig.FS is required")
+	}
+	shared, err := globFS(conf.FS, append(append([]string{}, conf.Layouts...), conf.Partials...))
+	if err != nil {
+		return nil, err
+	}
+	pages, err := globFS(conf.FS, conf.Pages)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("gee: no page templates match %v", conf.Pages)
+	}
+	base := template.New("").Funcs(funcMap)
+	if err = parseFiles(base, conf.FS, shared); err != nil {
+		return nil, err
+	}
+	r := &layoutRender{pages: make(map[string]*template.Template, len(pages)), layout: conf.Layout}
+	for _, page := range pages {
+		t, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if err = parseFiles(t, conf.FS, []string{page}); err != nil {
+			return nil, err
+		}
+		r.pages[page] = t
+	}
+	return r, nil
+}
+
+// 设置新的加载函数并立即加载一次, 加载失败时仍保留加载函数,
+// 以便之后调用 SetFuncMap 补充缺少的模板函数时重新加载
+func (engine *Engine) loadHTML(loader htmlLoader) error {
+	engine.htmlLoader = loader
+	render, err := loader(engine.templateFuncs())
+	if err != nil {
+		return err
+	}
+	engine.htmlRender = render
+	return nil
+}
+
+// SetFuncMap 设置模板函数, 在加载模板之后调用时会重新加载模板
+func (engine *Engine) SetFuncMap(funcMap template.FuncMap) error {
+	engine.funcMap = funcMap
+	if engine.htmlLoader != nil {
+		return engine.loadHTML(engine.htmlLoader)
+	}
+	return nil
+}
+
+// LoadHTMLGlob 从磁盘加载匹配 pattern 的模板, 模板名为文件名
+func (engine *Engine) LoadHTMLGlob(pattern string) error {
+	return engine.loadHTML(func(funcMap template.FuncMap) (HTMLRender, error) {
+		t, err := template.New("").Funcs(funcMap).ParseGlob(pattern)
+		return htmlSet{t}, err
+	})
+}
+
+// LoadHTMLFS 从 fs.FS (例如 embed.FS) 加载模板, 模板名为文件名
+func (engine *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) error {
+	return engine.loadHTML(func(funcMap template.FuncMap) (HTMLRender, error) {
+		t, err := template.New("").Funcs(funcMap).ParseFS(fsys, patterns...)
+		return htmlSet{t}, err
+	})
+}
+
+// LoadHTMLTemplates 加载带布局的模板, 页面模板名为相对 FS 的路径, 例如 pages/index.tmpl
+func (engine *Engine) LoadHTMLTemplates(conf TemplateConfig) error {
+	return engine.loadHTML(func(funcMap template.FuncMap) (HTMLRender, error) {
+		return loadLayouts(conf, funcMap)
+	})
+}
+
+// SetHTMLTemplate 直接使用已解析好的模板
+func (engine *Engine) SetHTMLTemplate(t *template.Template) {
+	engine.htmlLoader = nil
+	engine.htmlRender = htmlSet{t}
+}
+
+// SetHTMLRender 使用自定义的 HTMLRender
+func (engine *Engine) SetHTMLRender(render HTMLRender) {
+	engine.htmlLoader = nil
+	engine.htmlRender = render
+}
+
+// 开启 HTMLAutoReload 时每次渲染前重新加载模板, 便于开发调试
+func (engine *Engine) currentHTMLRender() (HTMLRender, error) {
+	if engine.HTMLAutoReload && engine.htmlLoader != nil {
+		return engine.htmlLoader(engine.templateFuncs())
+	}
+	if engine.htmlRender == nil {
+		return nil, fmt.Errorf("gee: html templates are not loaded")
+	}
+	return engine.htmlRender, nil
+}
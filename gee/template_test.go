@@ -0,0 +1,85 @@
+package gee
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var templateFS = fstest.MapFS{
+	"layouts/base.tmpl":  {Data: []byte(`<title>{{block "title" .}}gee{{end}}</title>{{template "partials/nav.tmpl" .}}{{block "content" .}}{{end}}`)},
+	"partials/nav.tmpl":  {Data: []byte(`<nav>{{upper .user}}</nav>`)},
+	"pages/index.tmpl":   {Data: []byte(`{{define "content"}}<p>index</p>{{end}}`)},
+	"pages/profile.tmpl": {Data: []byte(`{{define "title"}}profile{{end}}{{define "content"}}<p>{{.user}}</p>{{end}}`)},
+	"static/app.css":     {Data: []byte(`body{}`)},
+}
+
+func TestLayoutTemplates(t *testing.T) {
+	r := New()
+	err := r.LoadHTMLTemplates(TemplateConfig{
+		FS:       templateFS,
+		Layouts:  []string{"layouts/*.tmpl"},
+		Partials: []string{"partials/*.tmpl"},
+		Pages:    []string{"pages/*.tmpl"},
+		Layout:   "layouts/base.tmpl",
+	})
+	if err == nil {
+		t.Fatal("loading templates that use an undefined func should fail")
+	}
+	// 加载之后再设置 funcMap 也会生效
+	if err = r.SetFuncMap(template.FuncMap{"upper": strings.ToUpper}); err != nil {
+		t.Fatal(err)
+	}
+	r.StaticFS("/assets", templateFS)
+	r.GET("/:page", func(c *Context) {
+		c.HTML(http.StatusOK, "pages/"+c.Param("page")+".tmpl", H{"user": "tiam"})
+	})
+
+	cases := map[string]string{
+		"/index":   "<title>gee</title><nav>TIAM</nav><p>index</p>",
+		"/profile": "<title>profile</title><nav>TIAM</nav><p>tiam</p>",
+	}
+	for path, body := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != body {
+			t.Fatalf("%s: unexpected body %q", path, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/assets/static/app.css", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "body{}" {
+		t.Fatalf("unexpected static response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestHTMLAutoReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "index.tmpl")
+	if err := os.WriteFile(file, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.HTMLAutoReload = true
+	if err := r.LoadHTMLGlob(filepath.Join(dir, "*.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index.tmpl", nil) })
+
+	for _, version := range []string{"v1", "v2"} {
+		if err := os.WriteFile(file, []byte(version), 0644); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Body.String() != version {
+			t.Fatalf("expect %s, got %q", version, w.Body.String())
+		}
+	}
+}
@@ -1,72 +1,202 @@
 package gee
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
-// 请求类型为根, 各建一颗trie树, 例如: GET
+// 节点类型, 匹配优先级: static > 带约束的 param > param > catchAll
+type nodeKind uint8
+
+const (
+	static   nodeKind = iota // 静态节点, 例如: /p/
+	param                    // 参数节点, 例如: :lang、:id<int>、{id:[0-9]+}
+	catchAll                 // 通配节点, 例如: *filepath
+)
+
+// 请求类型为根, 各建一颗压缩前缀树(radix tree), 例如: GET
 type node struct {
-	pattern  string  // 待匹配路由, 例如: /p/:lang
-	part     string  //路由的一部分, 例如: :lang
-	children []*node // 子节点, 例如: [doc, tutorial, intro]
-	isWild   bool    // 是否模糊匹配, part 含有 : 或 * 时为true
+	kind       nodeKind
+	prefix     string            // 静态节点为压缩后的公共前缀, 参数/通配节点为路由中的原始写法, 例如 :id<int>
+	key        string            // 参数/通配节点的参数名
+	constraint string            // 参数约束的标识, 例如 <int>, 没有约束时为空
+	match      func(string) bool // 参数约束的检查函数, 没有约束时为 nil
+	pattern    string            // 待匹配路由, 仅终止节点非空, 例如: /p/:lang
+	handlers   []HandlerFunc     // 终止节点对应的处理链 [middlewares..., handler]
+	groupSize  int               // handlers 中来自路由组的中间件个数
+	indices    string            // 静态子节点的首字节, 与 children 一一对应
+	children   []*node           // 静态子节点, 首字节互不相同
+	params     []*node           // 参数子节点, 每种约束一个, 带约束的在前, 不带约束的最多一个且在最后
+	anyChild   *node             // 通配子节点, 同一位置只允许一个
 }
 
-// 寻找第一个匹配成功的节点
-func (n *node) matchChild(part string) *node {
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			return child
+// 寻找首字节为 c 的静态子节点
+func (n *node) staticChild(c byte) *node {
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] == c {
+			return n.children[i]
 		}
 	}
 	return nil
 }
 
-// 寻找所有匹配成功的节点
-func (n *node) matchChildren(part string) []*node {
-	children := []*node{}
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			children = append(children, child)
+// 插入节点: 路由注册, 路由冲突时 panic, 返回终止节点
+func (n *node) insert(pattern string, handlers []HandlerFunc) *node {
+	path := pattern
+	for len(path) > 0 {
+		switch path[0] {
+		case ':', '{':
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			wildcard := path[:end]
+			n = n.insertParam(wildcard, parseWildcard(wildcard, pattern), pattern)
+			path = path[end:]
+		case '*':
+			if strings.IndexByte(path, '/') >= 0 {
+				panic(fmt.Sprintf("gee: catch-all must be the last segment in route '%s'", pattern))
+			}
+			spec := parseWildcard(path, pattern)
+			if n.anyChild == nil {
+				n.anyChild = &node{kind: catchAll, prefix: path, key: spec.key}
+			} else if n.anyChild.prefix != path {
+				panic(fmt.Sprintf("gee: catch-all '%s' in route '%s' conflicts with existing catch-all '%s'",
+					path, pattern, n.anyChild.prefix))
+			}
+			n = n.anyChild
+			path = ""
+		default:
+			end := strings.IndexAny(path, ":*{")
+			if end < 0 {
+				end = len(path)
+			} else if path[end-1] != '/' {
+				panic(fmt.Sprintf("gee: wildcard must start a path segment in route '%s'", pattern))
+			}
+			n = n.insertStatic(path[:end])
+			path = path[end:]
 		}
 	}
-	return children
+	if n.pattern != "" {
+		panic(fmt.Sprintf("gee: route '%s' conflicts with existing route '%s'", pattern, n.pattern))
+	}
+	n.pattern = pattern
+	n.handlers = handlers
+	return n
 }
 
-// 插入节点: 路由注册
-func (n *node) insert(pattern string, parts []string, height int) {
-	if len(parts) == height {
-		n.pattern = pattern
-		return
+// 解析参数段, 不合法时 panic
+func parseWildcard(wildcard string, pattern string) paramSpec {
+	spec, _, err := parseSegment(wildcard)
+	if err != nil {
+		panic(fmt.Sprintf("gee: %v in route '%s'", err, pattern))
 	}
+	return spec
+}
 
-	part := parts[height]
-	child := n.matchChild(part)
-	if child == nil {
-		child = &node{
-			part:   part,
-			isWild: part[0] == ':' || part[0] == '*',
+// 插入参数子节点: 约束相同的参数共用节点且参数名必须一致, 带约束的节点按注册顺序排在不带约束的节点之前
+func (n *node) insertParam(wildcard string, spec paramSpec, pattern string) *node {
+	constraint := spec.constraint()
+	for _, child := range n.params {
+		if child.constraint != constraint {
+			continue
+		}
+		if child.key != spec.key {
+			panic(fmt.Sprintf("gee: wildcard '%s' in route '%s' conflicts with existing wildcard '%s'",
+				wildcard, pattern, child.prefix))
 		}
-		n.children = append(n.children, child)
+		return child
 	}
-	// 递归插入
-	child.insert(pattern, parts, height+1)
+	child := &node{kind: param, prefix: wildcard, key: spec.key, constraint: constraint, match: spec.matcher()}
+	i := len(n.params)
+	if child.match != nil && i > 0 && n.params[i-1].match == nil {
+		i--
+	}
+	n.params = append(n.params, nil)
+	copy(n.params[i+1:], n.params[i:])
+	n.params[i] = child
+	return child
 }
 
-// 查询节点: 路由发现
-func (n *node) search(parts []string, height int) *node {
-	// 若 遍历完毕parts 或 发现通配符 *
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
-		if n.pattern == "" {
-			return nil
+// 插入静态前缀, 必要时分裂已有节点, 返回前缀结束处的节点
+func (n *node) insertStatic(path string) *node {
+	for {
+		child := n.staticChild(path[0])
+		if child == nil {
+			child = &node{kind: static, prefix: path}
+			n.indices += string(path[0])
+			n.children = append(n.children, child)
+			return child
+		}
+		i := longestCommonPrefix(path, child.prefix)
+		if i < len(child.prefix) {
+			// 分裂: child 保留公共前缀, 原有内容下沉为新的子节点
+			split := *child
+			split.prefix = child.prefix[i:]
+			*child = node{
+				kind:     static,
+				prefix:   child.prefix[:i],
+				indices:  string(split.prefix[0]),
+				children: []*node{&split},
+			}
 		}
-		return n
+		if i == len(path) {
+			return child
+		}
+		n = child
+		path = path[i:]
 	}
-	part := parts[height]
-	children := n.matchChildren(part)
-	for _, child := range children {
-		target := child.search(parts, height+1)
-		if target != nil {
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// 查询节点: 路由发现, 参数按顺序追加到 params 中, 失败时回溯
+// params 容量足够时整个查询不产生内存分配
+func (n *node) search(path string, params *Params) *node {
+	if path == "" {
+		if n.pattern != "" {
+			return n
+		}
+		return nil
+	}
+	// 1. 静态子节点
+	if child := n.staticChild(path[0]); child != nil && strings.HasPrefix(path, child.prefix) {
+		if target := child.search(path[len(child.prefix):], params); target != nil {
 			return target
 		}
 	}
+	// 2. 参数子节点, 匹配到下一个 / 为止, 不允许为空; 不满足约束时尝试下一个参数子节点
+	if len(n.params) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			value := path[:end]
+			for _, child := range n.params {
+				if child.match != nil && !child.match(value) {
+					continue
+				}
+				*params = append(*params, Param{Key: child.key, Value: value})
+				if target := child.search(path[end:], params); target != nil {
+					return target
+				}
+				*params = (*params)[:len(*params)-1]
+			}
+		}
+	}
+	// 3. 通配子节点, 匹配剩余的全部路径
+	if child := n.anyChild; child != nil && child.pattern != "" {
+		if child.key != "" {
+			*params = append(*params, Param{Key: child.key, Value: path})
+		}
+		return child
+	}
 	return nil
 }
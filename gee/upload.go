@@ -0,0 +1,158 @@
+package gee
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
+// ErrBodyTooLarge 请求体超过 BodyLimit 设置的大小
+var ErrBodyTooLarge = errors.New("gee: request body too large")
+
+func (c *Context) maxMultipartMemory() int64 {
+	if c.engine == nil {
+		return defaultMultipartMemory
+	}
+	return c.engine.MaxMultipartMemory
+}
+
+// MultipartForm 解析 multipart 表单, 超过 Engine.MaxMultipartMemory 的部分写入临时文件
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.Req.ParseMultipartForm(c.maxMultipartMemory()); err != nil {
+		return nil, err
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// FormFile 返回表单中 name 对应的第一个文件
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if c.Req.MultipartForm == nil {
+		if err := c.Req.ParseMultipartForm(c.maxMultipartMemory()); err != nil {
+			return nil, err
+		}
+	}
+	f, fh, err := c.Req.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return fh, nil
+}
+
+// SaveUploadedFile 将上传的文件保存到 dst, 目录不存在时自动创建
+// file.Filename 来自客户端, 拼接 dst 时应使用 filepath.Base 等方式过滤
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return saveFile(src, dst)
+}
+
+func saveFile(src io.Reader, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, src); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// MultipartReader 以流的方式逐个读取 multipart 的各个部分, 不会缓存请求体
+// 与 MultipartForm、FormFile 互斥
+func (c *Context) MultipartReader() (*multipart.Reader, error) {
+	return c.Req.MultipartReader()
+}
+
+// SaveUploadedStream 边读取请求体边把表单字段 name 对应的文件写入 dst, 适合大文件上传,
+// 其他字段会被跳过, 返回写入的字节数
+func (c *Context) SaveUploadedStream(name string, dst string) (int64, error) {
+	reader, err := c.MultipartReader()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return 0, fmt.Errorf("gee: no file named %q in multipart form", name)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if part.FormName() != name || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		counter := &countingReader{r: part}
+		err = saveFile(counter, dst)
+		part.Close()
+		return counter.n, err
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// BodyLimit 限制请求体大小, Content-Length 超出时直接返回 413,
+// 未声明长度的请求在读取超出 limit 时返回 ErrBodyTooLarge, 处理方法未写响应时同样返回 413
+func BodyLimit(limit int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Req.ContentLength > limit {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		body := &limitedBody{ReadCloser: c.Req.Body, remaining: limit}
+		c.Req.Body = body
+		c.Next()
+		if body.exceeded && !c.W.Written() {
+			c.String(http.StatusRequestEntityTooLarge, "413 REQUEST ENTITY TOO LARGE\n")
+		}
+	}
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	// 多读一个字节以判断是否超出
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		b.exceeded = true
+		return n, ErrBodyTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
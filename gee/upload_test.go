@@ -0,0 +1,92 @@
+package gee
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newUploadRequest(t *testing.T, field string, filename string, content string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("title", "avatar")
+	fw, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.WriteString(fw, content)
+	_ = mw.Close()
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestSaveUploadedFile(t *testing.T) {
+	dir := t.TempDir()
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		dst := filepath.Join(dir, "sub", filepath.Base(file.Filename))
+		if err := c.SaveUploadedFile(file, dst); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%s %s", c.PostForm("title"), file.Filename)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest(t, "file", "a.png", "png-data"))
+	if w.Code != http.StatusOK || w.Body.String() != "avatar a.png" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "sub", "a.png")); string(data) != "png-data" {
+		t.Fatalf("unexpected saved content %q", data)
+	}
+}
+
+func TestSaveUploadedStreamWithLimit(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "big.bin")
+	r := New()
+	r.POST("/upload", BodyLimit(1024), func(c *Context) {
+		n, err := c.SaveUploadedStream("file", dst)
+		if err != nil {
+			return
+		}
+		c.String(http.StatusOK, "%d", n)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest(t, "file", "big.bin", strings.Repeat("x", 100)))
+	if w.Code != http.StatusOK || w.Body.String() != "100" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	// 声明了 Content-Length 的请求直接拒绝
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest(t, "file", "big.bin", strings.Repeat("x", 2048)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect 413, got %d", w.Code)
+	}
+
+	// 未声明长度的请求在读取时超限
+	req := newUploadRequest(t, "file", "big.bin", strings.Repeat("x", 2048))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect 413 for chunked body, got %d", w.Code)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatal("partial upload should be removed")
+	}
+}
@@ -0,0 +1,279 @@
+package gee
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// StructValidator 校验 Bind 解析后的结构体, 可替换为其他实现
+type StructValidator interface {
+	ValidateStruct(obj interface{}) error
+}
+
+// Validator Bind 系列方法使用的校验器, 设为 nil 时不做校验
+var Validator StructValidator = NewValidator()
+
+func validate(obj interface{}) error {
+	if Validator == nil {
+		return nil
+	}
+	return Validator.ValidateStruct(obj)
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`           // 字段路径, 例如 Address.City
+	Tag     string `json:"tag"`             // 未通过的规则, 例如 min
+	Param   string `json:"param,omitempty"` // 规则参数, 例如 min=3 中的 3
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors 校验失败的所有字段, 可直接作为 400 响应的 JSON 返回
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidationFunc 校验规则, param 为 tag 中 = 之后的参数, 返回 false 表示校验失败
+type ValidationFunc func(field reflect.Value, param string) bool
+
+// DefaultValidator 基于 struct tag 的校验器, 例如 `binding:"required,min=3"`
+type DefaultValidator struct {
+	TagName string
+
+	mu    sync.RWMutex
+	rules map[string]ValidationFunc
+}
+
+func NewValidator() *DefaultValidator {
+	return &DefaultValidator{
+		TagName: "binding",
+		rules: map[string]ValidationFunc{
+			"required": hasValue,
+			"min":      checkMin,
+			"max":      checkMax,
+			"len":      checkLen,
+			"oneof":    checkOneOf,
+			"email":    checkEmail,
+		},
+	}
+}
+
+// RegisterValidation 注册自定义规则, 与已有规则同名时覆盖
+func (v *DefaultValidator) RegisterValidation(tag string, fn ValidationFunc) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[tag] = fn
+}
+
+func (v *DefaultValidator) ValidateStruct(obj interface{}) error {
+	val := reflect.ValueOf(obj)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	v.validateStruct(val, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (v *DefaultValidator) validateStruct(val reflect.Value, namespace string, errs *ValidationErrors) {
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		name := sf.Name
+		if namespace != "" {
+			name = namespace + "." + name
+		}
+		field := val.Field(i)
+		if tag := sf.Tag.Get(v.TagName); tag != "" && tag != "-" {
+			if !v.validateField(field, name, tag, errs) {
+				continue
+			}
+		}
+		v.validateNested(field, name, errs)
+	}
+}
+
+// 递归校验嵌套结构体以及结构体切片
+func (v *DefaultValidator) validateNested(field reflect.Value, name string, errs *ValidationErrors) {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return
+		}
+		field = field.Elem()
+	}
+	switch field.Kind() {
+	case reflect.Struct:
+		if field.Type() != timeType {
+			v.validateStruct(field, name, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			v.validateNested(field.Index(i), fmt.Sprintf("%s[%d]", name, i), errs)
+		}
+	}
+}
+
+// 按 tag 中的规则依次校验, 第一个失败的规则记入 errs 并返回 false
+func (v *DefaultValidator) validateField(field reflect.Value, name string, tag string, errs *ValidationErrors) bool {
+	for _, rule := range strings.Split(tag, ",") {
+		rule = strings.TrimSpace(rule)
+		param := ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			rule, param = rule[:i], rule[i+1:]
+		}
+		if rule == "omitempty" {
+			if !hasValue(field, "") {
+				return true
+			}
+			continue
+		}
+		v.mu.RLock()
+		fn, ok := v.rules[rule]
+		v.mu.RUnlock()
+		if !ok {
+			panic(fmt.Sprintf("gee: undefined validation rule '%s' on field '%s'", rule, name))
+		}
+		// 除 required 外, nil 指针视为未填写, 不做校验
+		target := field
+		if rule != "required" {
+			for target.Kind() == reflect.Ptr {
+				if target.IsNil() {
+					return true
+				}
+				target = target.Elem()
+			}
+		}
+		if !fn(target, param) {
+			*errs = append(*errs, FieldError{
+				Field:   name,
+				Tag:     rule,
+				Param:   param,
+				Message: fieldErrorMessage(name, rule, param),
+			})
+			return false
+		}
+	}
+	return true
+}
+
+func fieldErrorMessage(name string, rule string, param string) string {
+	switch rule {
+	case "required":
+		return fmt.Sprintf("%s is required", name)
+	case "min":
+		return fmt.Sprintf("%s must be at least %s", name, param)
+	case "max":
+		return fmt.Sprintf("%s must be at most %s", name, param)
+	case "len":
+		return fmt.Sprintf("%s must have length %s", name, param)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", name, param)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", name)
+	default:
+		return fmt.Sprintf("%s failed on the '%s' rule", name, rule)
+	}
+}
+
+func hasValue(field reflect.Value, _ string) bool {
+	switch field.Kind() {
+	case reflect.Invalid:
+		return false
+	case reflect.Slice, reflect.Map:
+		return field.Len() > 0
+	case reflect.Ptr, reflect.Interface, reflect.Chan, reflect.Func:
+		return !field.IsNil()
+	default:
+		return !field.IsZero()
+	}
+}
+
+// 字符串、切片、map 比较长度, 数字比较数值
+func compare(field reflect.Value, param string) (int, bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("gee: invalid validation param '%s'", param))
+	}
+	var x float64
+	switch field.Kind() {
+	case reflect.String:
+		x = float64(utf8.RuneCountInString(field.String()))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		x = float64(field.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x = float64(field.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x = float64(field.Uint())
+	case reflect.Float32, reflect.Float64:
+		x = field.Float()
+	default:
+		return 0, false
+	}
+	switch {
+	case x < n:
+		return -1, true
+	case x > n:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+func checkMin(field reflect.Value, param string) bool {
+	c, ok := compare(field, param)
+	return ok && c >= 0
+}
+
+func checkMax(field reflect.Value, param string) bool {
+	c, ok := compare(field, param)
+	return ok && c <= 0
+}
+
+func checkLen(field reflect.Value, param string) bool {
+	c, ok := compare(field, param)
+	return ok && c == 0
+}
+
+// oneof 的候选值以空格分隔, 例如 oneof=red green blue
+func checkOneOf(field reflect.Value, param string) bool {
+	s := fmt.Sprint(field.Interface())
+	for _, candidate := range strings.Fields(param) {
+		if s == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+var emailRegexp = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)+$`)
+
+func checkEmail(field reflect.Value, _ string) bool {
+	return field.Kind() == reflect.String && emailRegexp.MatchString(field.String())
+}
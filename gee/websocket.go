@@ -0,0 +1,693 @@
+package gee
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket 消息类型, 与 RFC 6455 的 opcode 一致
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// WebSocket 关闭码
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	websocketGUID          = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	defaultWebSocketLimit  = 1 << 20
+	maxControlPayload      = 125
+	websocketCloseDeadline = time.Second
+)
+
+var (
+	ErrWebSocketClosed    = errors.New("gee: websocket connection is closed")
+	ErrWebSocketHandshake = errors.New("gee: websocket handshake failed")
+	ErrMessageTooBig      = errors.New("gee: websocket message exceeds read limit")
+)
+
+// CloseError 对端发送的关闭帧, 或因协议错误由本端发出的关闭
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("gee: websocket closed with code %d %s", e.Code, e.Text)
+}
+
+// WebSocketConfig WebSocket 握手和连接配置, 零值即为默认配置
+type WebSocketConfig struct {
+	ReadLimit     int64                      // 单条消息(包括所有分片)的最大字节数, 默认 1MB, 超过时以 1009 关闭
+	FragmentSize  int                        // 发送时每个分片的最大字节数, 0 表示不分片
+	Subprotocols  []string                   // 服务端支持的子协议, 按优先级排列
+	CheckOrigin   func(r *http.Request) bool // 校验 Origin, 默认只允许同源或没有 Origin 的请求
+	WriteDeadline time.Duration              // 每次写入的超时时间, 0 表示不限制
+}
+
+// WebSocketConn 一个 WebSocket 连接, ReadMessage 只能在一个协程中调用, 写方法可以并发调用
+type WebSocketConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	isServer    bool
+	readLimit   int64
+	fragment    int
+	writeWait   time.Duration
+	subprotocol string
+
+	wmu       sync.Mutex
+	closeSent bool
+	closeOnce sync.Once
+
+	pongHandler func(data []byte)
+}
+
+// IsWebSocket 判断请求是否为 WebSocket 握手请求
+func (c *Context) IsWebSocket() bool {
+	return headerContainsToken(c.Req.Header, "Connection", "upgrade") &&
+		strings.EqualFold(c.Req.Header.Get("Upgrade"), "websocket")
+}
+
+// Upgrade 使用默认配置将请求升级为 WebSocket 连接
+func (c *Context) Upgrade() (*WebSocketConn, error) {
+	return c.UpgradeWithConfig(WebSocketConfig{})
+}
+
+// UpgradeWithConfig 完成 WebSocket 握手并接管连接, 握手失败时已经写出错误响应;
+// 升级后的连接不受 Engine.Shutdown 管理, 需要自行关闭, 例如 r.OnShutdown(hub.Close)
+func (c *Context) UpgradeWithConfig(conf WebSocketConfig) (*WebSocketConn, error) {
+	fail := func(code int, reason string) (*WebSocketConn, error) {
+		c.String(code, "%s\n", reason)
+		return nil, fmt.Errorf("%w: %s", ErrWebSocketHandshake, reason)
+	}
+	if c.Req.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "websocket handshake requires GET")
+	}
+	if !c.IsWebSocket() {
+		return fail(http.StatusBadRequest, "missing websocket upgrade headers")
+	}
+	if c.Req.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		return fail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := c.Req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := conf.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(c.Req) {
+		return fail(http.StatusForbidden, "websocket origin not allowed")
+	}
+	// 按服务端的优先级选择客户端提供的子协议
+	subprotocol := ""
+	offers := strings.Split(c.Req.Header.Get("Sec-WebSocket-Protocol"), ",")
+	for _, supported := range conf.Subprotocols {
+		for _, offered := range offers {
+			if subprotocol == "" && strings.TrimSpace(offered) == supported {
+				subprotocol = supported
+			}
+		}
+	}
+
+	// 记录状态码供日志使用, 101 响应由下面直接写入连接
+	c.Status(http.StatusSwitchingProtocols)
+	conn, brw, err := c.W.Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, err.Error())
+	}
+	var resp strings.Builder
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	resp.WriteString("Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n")
+	if subprotocol != "" {
+		resp.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	resp.WriteString("\r\n")
+	// 握手前设置的超时对升级后的连接不再适用
+	_ = conn.SetDeadline(time.Time{})
+	if _, err = io.WriteString(conn, resp.String()); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	ws := newWebSocketConn(conn, brw.Reader, true, conf)
+	ws.subprotocol = subprotocol
+	return ws, nil
+}
+
+func newWebSocketConn(conn net.Conn, br *bufio.Reader, isServer bool, conf WebSocketConfig) *WebSocketConn {
+	if conf.ReadLimit <= 0 {
+		conf.ReadLimit = defaultWebSocketLimit
+	}
+	return &WebSocketConn{
+		conn:      conn,
+		br:        br,
+		isServer:  isServer,
+		readLimit: conf.ReadLimit,
+		fragment:  conf.FragmentSize,
+		writeWait: conf.WriteDeadline,
+	}
+}
+
+func websocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// 没有 Origin 的请求通常不是来自浏览器, 直接放行
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Subprotocol 协商得到的子协议
+func (ws *WebSocketConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+func (ws *WebSocketConn) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+func (ws *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+// SetPongHandler 设置收到 pong 时的回调, 通常用于在心跳后延长读超时
+func (ws *WebSocketConn) SetPongHandler(fn func(data []byte)) {
+	ws.pongHandler = fn
+}
+
+// ReadMessage 读取一条完整的消息, 分片会被合并; ping 自动回复 pong,
+// 收到关闭帧时回复关闭帧并返回 *CloseError
+func (ws *WebSocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		fin, opcode, payload, err := ws.readFrame(int64(len(data)))
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err = ws.writeControl(PongMessage, payload, 0); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if ws.pongHandler != nil {
+				ws.pongHandler(payload)
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, ws.handleClose(payload)
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			if messageType != 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType = int(opcode)
+		}
+		data = append(data, payload...)
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(data) {
+				return 0, nil, ws.fail(CloseInvalidFramePayloadData, "invalid utf-8 in text message")
+			}
+			if data == nil {
+				data = []byte{}
+			}
+			return messageType, data, nil
+		}
+	}
+}
+
+// ReadJSON 读取一条消息并按 JSON 解码
+func (ws *WebSocketConn) ReadJSON(v interface{}) error {
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// 读取一个帧并校验, received 为当前消息已读取的字节数
+func (ws *WebSocketConn) readFrame(received int64) (fin bool, opcode byte, payload []byte, err error) {
+	var head [8]byte
+	if _, err = io.ReadFull(ws.br, head[:2]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	length := int64(head[1] & 0x7f)
+
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, ws.fail(CloseProtocolError, "reserved bits must be zero")
+	}
+	switch opcode {
+	case continuationFrame, TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if !fin || length > maxControlPayload {
+			return false, 0, nil, ws.fail(CloseProtocolError, "invalid control frame")
+		}
+	default:
+		return false, 0, nil, ws.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode))
+	}
+	// 客户端发送的帧必须掩码, 服务端发送的帧不能掩码
+	if masked != ws.isServer {
+		return false, 0, nil, ws.fail(CloseProtocolError, "invalid frame masking")
+	}
+
+	switch length {
+	case 126:
+		if _, err = io.ReadFull(ws.br, head[:2]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(head[:2]))
+	case 127:
+		if _, err = io.ReadFull(ws.br, head[:8]); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint64(head[:8])
+		if n>>63 != 0 {
+			return false, 0, nil, ws.fail(CloseProtocolError, "invalid payload length")
+		}
+		length = int64(n)
+	}
+	if opcode < CloseMessage && received+length > ws.readLimit {
+		_ = ws.fail(CloseMessageTooBig, "message too big")
+		return false, 0, nil, ErrMessageTooBig
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(ws.br, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.br, payload); err != nil {
+		return
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return fin, opcode, payload, nil
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i&3]
+	}
+}
+
+// 处理对端的关闭帧: 校验关闭码, 回复关闭帧后关闭连接
+func (ws *WebSocketConn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return ws.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return ws.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return ws.fail(CloseInvalidFramePayloadData, "invalid utf-8 in close reason")
+		}
+	}
+	var reply []byte
+	if closeErr.Code != CloseNoStatusReceived {
+		reply = payload[:2]
+	}
+	_ = ws.writeControl(CloseMessage, reply, 0)
+	ws.closeConn()
+	return closeErr
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// 对端违反协议时发送关闭帧并断开连接
+func (ws *WebSocketConn) fail(code int, reason string) error {
+	_ = ws.WriteClose(code, reason)
+	ws.closeConn()
+	return &CloseError{Code: code, Text: reason}
+}
+
+// WriteMessage 发送一条消息, 设置了 FragmentSize 时按分片发送
+func (ws *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	return ws.writeMessage(messageType, data, 0)
+}
+
+// timeout 为每一帧的写入超时, 0 表示使用 WriteDeadline
+func (ws *WebSocketConn) writeMessage(messageType int, data []byte, timeout time.Duration) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		return ws.writeControl(byte(messageType), data, timeout)
+	default:
+		return fmt.Errorf("gee: invalid websocket message type %d", messageType)
+	}
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	if ws.closeSent {
+		return ErrWebSocketClosed
+	}
+	opcode := byte(messageType)
+	for {
+		chunk := data
+		if ws.fragment > 0 && len(chunk) > ws.fragment {
+			chunk = data[:ws.fragment]
+		}
+		data = data[len(chunk):]
+		if err := ws.writeFrame(len(data) == 0, opcode, chunk, timeout); err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		opcode = continuationFrame
+	}
+}
+
+// WriteJSON 将 v 编码为 JSON 并作为文本消息发送
+func (ws *WebSocketConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(TextMessage, data)
+}
+
+// WriteClose 发送关闭帧, 之后不能再发送消息; 应继续调用 ReadMessage 直到收到对端的关闭帧
+func (ws *WebSocketConn) WriteClose(code int, reason string) error {
+	return ws.writeClose(code, reason, 0)
+}
+
+// reason 超出控制帧长度时截断, 截断位置退回到完整字符的边界, 保证仍是合法的 UTF-8
+func (ws *WebSocketConn) writeClose(code int, reason string, timeout time.Duration) error {
+	if n := maxControlPayload - 2; len(reason) > n {
+		for n > 0 && !utf8.RuneStart(reason[n]) {
+			n--
+		}
+		reason = reason[:n]
+	}
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	return ws.writeControl(CloseMessage, payload, timeout)
+}
+
+// 控制帧可以插入在分片消息之间, 但要与其他写操作互斥
+func (ws *WebSocketConn) writeControl(opcode byte, payload []byte, timeout time.Duration) error {
+	if len(payload) > maxControlPayload {
+		return errors.New("gee: websocket control frame payload too large")
+	}
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	if ws.closeSent {
+		return ErrWebSocketClosed
+	}
+	if opcode == CloseMessage {
+		ws.closeSent = true
+	}
+	return ws.writeFrame(true, opcode, payload, timeout)
+}
+
+// 调用方需持有 wmu, 写入超时也在持有 wmu 时设置, 避免与其他写操作互相覆盖
+func (ws *WebSocketConn) writeFrame(fin bool, opcode byte, payload []byte, timeout time.Duration) error {
+	frame := make([]byte, 0, 14+len(payload))
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame = append(frame, b0)
+	var maskBit byte
+	if !ws.isServer {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126, byte(n>>8), byte(n))
+	default:
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(n))
+		frame = append(append(frame, maskBit|127), size[:]...)
+	}
+	if ws.isServer {
+		frame = append(frame, payload...)
+	} else {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	}
+	if timeout == 0 {
+		timeout = ws.writeWait
+	}
+	// 每次写入都重新设置, 清除之前广播等操作留下的超时
+	deadline := time.Time{}
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	_ = ws.conn.SetWriteDeadline(deadline)
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+// Close 发送 1000 关闭帧并断开连接, 不等待对端回复
+func (ws *WebSocketConn) Close() error {
+	return ws.CloseWithCode(CloseNormalClosure, "")
+}
+
+// CloseWithCode 发送指定的关闭帧并断开连接
+func (ws *WebSocketConn) CloseWithCode(code int, reason string) error {
+	err := ws.writeClose(code, reason, websocketCloseDeadline)
+	ws.closeConn()
+	if err == ErrWebSocketClosed {
+		return nil
+	}
+	return err
+}
+
+func (ws *WebSocketConn) closeConn() {
+	ws.closeOnce.Do(func() { _ = ws.conn.Close() })
+}
+
+// DialWebSocket 连接 WebSocket 服务端, rawURL 的 scheme 为 ws、wss、http 或 https,
+// 握手失败时返回服务端的响应, 主要用于测试和服务间通信
+func DialWebSocket(ctx context.Context, rawURL string, header http.Header) (*WebSocketConn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	secure := false
+	switch u.Scheme {
+	case "ws", "http":
+		u.Scheme = "http"
+	case "wss", "https":
+		u.Scheme, secure = "https", true
+	default:
+		return nil, nil, fmt.Errorf("gee: unsupported websocket scheme %q", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		if secure {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secure {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, nil, err
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var nonce [16]byte
+	if _, err = rand.Read(nonce[:]); err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: http.Header{}}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err = req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		_ = conn.Close()
+		return nil, resp, ErrWebSocketHandshake
+	}
+	_ = conn.SetDeadline(time.Time{})
+	ws := newWebSocketConn(conn, br, false, WebSocketConfig{})
+	ws.subprotocol = resp.Header.Get("Sec-WebSocket-Protocol")
+	return ws, resp, nil
+}
+
+// Hub 管理一组 WebSocket 连接并广播消息
+type Hub struct {
+	WriteTimeout time.Duration // 每个连接的广播写入超时, 超时的连接会被移除, 默认 10 秒
+
+	mu    sync.RWMutex
+	conns map[*WebSocketConn]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{WriteTimeout: 10 * time.Second, conns: make(map[*WebSocketConn]struct{})}
+}
+
+func (h *Hub) Register(ws *WebSocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[ws] = struct{}{}
+}
+
+func (h *Hub) Unregister(ws *WebSocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, ws)
+}
+
+// Len 当前连接数
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// Broadcast 向所有连接并发发送消息, 发送失败的连接会被关闭并移除
+func (h *Hub) Broadcast(messageType int, data []byte) {
+	h.mu.RLock()
+	conns := make([]*WebSocketConn, 0, len(h.conns))
+	for ws := range h.conns {
+		conns = append(conns, ws)
+	}
+	h.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, ws := range conns {
+		wg.Add(1)
+		go func(ws *WebSocketConn) {
+			defer wg.Done()
+			if err := ws.writeMessage(messageType, data, h.WriteTimeout); err != nil {
+				h.Unregister(ws)
+				ws.closeConn()
+			}
+		}(ws)
+	}
+	wg.Wait()
+}
+
+// BroadcastJSON 将 v 编码为 JSON 后广播
+func (h *Hub) BroadcastJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h.Broadcast(TextMessage, data)
+	return nil
+}
+
+// Close 以 1001 关闭所有连接, 可以注册到 Engine.OnShutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	conns := h.conns
+	h.conns = make(map[*WebSocketConn]struct{})
+	h.mu.Unlock()
+	for ws := range conns {
+		_ = ws.CloseWithCode(CloseGoingAway, "server shutdown")
+	}
+}
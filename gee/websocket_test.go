@@ -0,0 +1,259 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func dialTestWebSocket(t *testing.T, srv *httptest.Server, path string) *WebSocketConn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ws, _, err := DialWebSocket(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return ws
+}
+
+func expectClose(t *testing.T, ws *WebSocketConn, code int) {
+	t.Helper()
+	for {
+		_, _, err := ws.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != code {
+			t.Fatalf("expected close %d, got %v", code, err)
+		}
+		return
+	}
+}
+
+func newWebSocketEngine(hub *Hub) *Engine {
+	r := New()
+	r.GET("/echo", func(c *Context) {
+		ws, err := c.UpgradeWithConfig(WebSocketConfig{ReadLimit: 1 << 16, FragmentSize: 100, Subprotocols: []string{"chat", "v2"}})
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			messageType, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err = ws.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	})
+	r.GET("/hub", func(c *Context) {
+		ws, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		hub.Register(ws)
+		defer hub.Unregister(ws)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	return r
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	srv := httptest.NewServer(newWebSocketEngine(NewHub()))
+	defer srv.Close()
+	ws := dialTestWebSocket(t, srv, "/echo")
+
+	if err := ws.WriteJSON(H{"msg": "天马"}); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]string
+	if err := ws.ReadJSON(&got); err != nil || got["msg"] != "天马" {
+		t.Fatalf("echo json = %v, %v", got, err)
+	}
+
+	// 客户端分片发送, 服务端合并后再按 100 字节分片返回
+	large := bytes.Repeat([]byte("0123456789"), 1000)
+	ws.fragment = 333
+	if err := ws.WriteMessage(BinaryMessage, large); err != nil {
+		t.Fatal(err)
+	}
+	messageType, data, err := ws.ReadMessage()
+	if err != nil || messageType != BinaryMessage || !bytes.Equal(data, large) {
+		t.Fatalf("echo binary: type %d, len %d, err %v", messageType, len(data), err)
+	}
+
+	pong := make(chan string, 1)
+	ws.SetPongHandler(func(data []byte) { pong <- string(data) })
+	if err = ws.WriteMessage(PingMessage, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if err = ws.WriteMessage(TextMessage, []byte("after ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err = ws.ReadMessage(); err != nil || string(data) != "after ping" || <-pong != "hi" {
+		t.Fatalf("ping/pong: %q %v", data, err)
+	}
+
+	if err = ws.WriteClose(CloseNormalClosure, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	expectClose(t, ws, CloseNormalClosure)
+}
+
+func TestWebSocketProtocolErrors(t *testing.T) {
+	srv := httptest.NewServer(newWebSocketEngine(NewHub()))
+	defer srv.Close()
+
+	// 超过 ReadLimit 以 1009 关闭
+	ws := dialTestWebSocket(t, srv, "/echo")
+	_ = ws.WriteMessage(BinaryMessage, make([]byte, 1<<16+1))
+	expectClose(t, ws, CloseMessageTooBig)
+
+	// 客户端发送未掩码的帧以 1002 关闭
+	ws = dialTestWebSocket(t, srv, "/echo")
+	ws.wmu.Lock()
+	ws.isServer = true
+	_ = ws.writeFrame(true, TextMessage, []byte("unmasked"), 0)
+	ws.isServer = false
+	ws.wmu.Unlock()
+	expectClose(t, ws, CloseProtocolError)
+
+	// 文本消息必须是合法的 UTF-8
+	ws = dialTestWebSocket(t, srv, "/echo")
+	_ = ws.WriteMessage(TextMessage, []byte{0xff, 0xfe})
+	expectClose(t, ws, CloseInvalidFramePayloadData)
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	srv := httptest.NewServer(newWebSocketEngine(NewHub()))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("plain GET status = %d", resp.StatusCode)
+	}
+
+	header := http.Header{"Origin": {"https://evil.com"}}
+	_, resp, err = DialWebSocket(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/echo", header)
+	if !errors.Is(err, ErrWebSocketHandshake) || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("cross origin handshake: %v", err)
+	}
+
+	header = http.Header{"Sec-WebSocket-Protocol": {"v2, chat"}}
+	ws, _, err := DialWebSocket(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/echo", header)
+	if err != nil || ws.Subprotocol() != "chat" {
+		t.Fatalf("subprotocol negotiation: %v", err)
+	}
+	ws.Close()
+}
+
+func TestWebSocketHub(t *testing.T) {
+	hub := NewHub()
+	srv := httptest.NewServer(newWebSocketEngine(hub))
+	defer srv.Close()
+
+	clients := make([]*WebSocketConn, 3)
+	for i := range clients {
+		clients[i] = dialTestWebSocket(t, srv, "/hub")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.Len() != len(clients) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := hub.BroadcastJSON(H{"event": "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	for i, ws := range clients {
+		var msg map[string]string
+		if err := ws.ReadJSON(&msg); err != nil || msg["event"] != "hello" {
+			t.Fatalf("client %d: %v %v", i, msg, err)
+		}
+	}
+	hub.Close()
+	for _, ws := range clients {
+		expectClose(t, ws, CloseGoingAway)
+	}
+	if hub.Len() != 0 {
+		t.Fatalf("hub len = %d", hub.Len())
+	}
+}
+
+func TestWebSocketWriteValidation(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	client := newWebSocketConn(c1, bufio.NewReader(c1), false, WebSocketConfig{})
+	server := newWebSocketConn(c2, bufio.NewReader(c2), true, WebSocketConfig{})
+
+	// 保留的 opcode 不能发送
+	for _, messageType := range []int{0, 3, 7, 11} {
+		if err := server.WriteMessage(messageType, nil); err == nil {
+			t.Fatalf("message type %d should be rejected", messageType)
+		}
+	}
+
+	// 超长的 reason 在字符边界截断
+	go func() {
+		_ = server.WriteClose(CloseNormalClosure, strings.Repeat("中", 50))
+		c2.Close()
+	}()
+	_, _, err := client.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseNormalClosure {
+		t.Fatalf("expected close %d, got %v", CloseNormalClosure, err)
+	}
+	if !utf8.ValidString(closeErr.Text) || closeErr.Text != strings.Repeat("中", 41) {
+		t.Fatalf("unexpected close reason %q", closeErr.Text)
+	}
+}
+
+func TestWebSocketBroadcastDeadline(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	client := newWebSocketConn(c1, bufio.NewReader(c1), false, WebSocketConfig{})
+	server := newWebSocketConn(c2, bufio.NewReader(c2), true, WebSocketConfig{})
+	received := make(chan string, 2)
+	go func() {
+		for {
+			_, data, err := client.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- string(data)
+		}
+	}()
+
+	hub := NewHub()
+	hub.WriteTimeout = 50 * time.Millisecond
+	hub.Register(server)
+	hub.Broadcast(TextMessage, []byte("hello"))
+	// 广播设置的超时不应影响之后没有超时的写入
+	time.Sleep(100 * time.Millisecond)
+	if err := server.WriteMessage(TextMessage, []byte("later")); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := <-received, <-received; a != "hello" || b != "later" {
+		t.Fatalf("unexpected messages %q %q", a, b)
+	}
+}
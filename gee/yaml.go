@@ -0,0 +1,174 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// 为了不引入第三方依赖, YAML 渲染先按 json tag 编码为 JSON,
+// 再保持字段顺序地转换为 block 风格的 YAML
+
+type yamlField struct {
+	key   string
+	value interface{}
+}
+
+type yamlMap []yamlField
+
+func marshalYAML(obj interface{}) ([]byte, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeYAML(&buf, v, 0, false)
+	if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// 逐个读取 JSON token, 对象解析为保持顺序的 yamlMap
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		m := yamlMap{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, yamlField{key: key.(string), value: value})
+		}
+		_, err = dec.Token() // }
+		return m, err
+	default: // '['
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token() // ]
+		return list, err
+	}
+}
+
+// inline 为 true 时第一行紧跟在 "- " 之后, 不输出缩进
+func writeYAML(buf *bytes.Buffer, v interface{}, indent int, inline bool) {
+	pad := strings.Repeat(" ", indent)
+	switch v := v.(type) {
+	case yamlMap:
+		if len(v) == 0 {
+			buf.WriteString("{}\n")
+			return
+		}
+		for i, f := range v {
+			if i > 0 || !inline {
+				buf.WriteString(pad)
+			}
+			buf.WriteString(yamlScalar(f.key))
+			buf.WriteByte(':')
+			writeYAMLValue(buf, f.value, indent)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]\n")
+			return
+		}
+		for i, item := range v {
+			if i > 0 || !inline {
+				buf.WriteString(pad)
+			}
+			buf.WriteString("- ")
+			if isYAMLBlock(item) {
+				writeYAML(buf, item, indent+2, true)
+			} else {
+				writeYAMLValue(buf, item, indent)
+			}
+		}
+	default:
+		buf.WriteString(yamlScalar(v))
+		buf.WriteByte('\n')
+	}
+}
+
+// 写入 "key:" 或 "- " 之后的值, 非空的对象和数组另起一行缩进
+func writeYAMLValue(buf *bytes.Buffer, v interface{}, indent int) {
+	if isYAMLBlock(v) {
+		buf.WriteByte('\n')
+		writeYAML(buf, v, indent+2, false)
+		return
+	}
+	if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] == ':' {
+		buf.WriteByte(' ')
+	}
+	writeYAML(buf, v, indent, true)
+}
+
+func isYAMLBlock(v interface{}) bool {
+	switch v := v.(type) {
+	case yamlMap:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		if yamlNeedsQuote(v) {
+			return strconv.Quote(v)
+		}
+		return v
+	}
+	return ""
+}
+
+// 可能被解析为其他类型或包含特殊字符的字符串需要加引号
+func yamlNeedsQuote(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.ContainsAny(s, ":#{}[],&*!|>'\"%@`\n\t\\") || s[0] == '-' || s[0] == '?' {
+		return true
+	}
+	return false
+}